@@ -2,17 +2,22 @@ package main
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
+	"sort"
 	"strconv"
 	"strings"
+	"text/tabwriter"
 	"time"
 
 	"github.com/leonardotrapani/hyprvoice/internal/bus"
 	"github.com/leonardotrapani/hyprvoice/internal/config"
 	"github.com/leonardotrapani/hyprvoice/internal/daemon"
 	"github.com/leonardotrapani/hyprvoice/internal/notify"
+	"github.com/leonardotrapani/hyprvoice/internal/recording"
+	"github.com/leonardotrapani/hyprvoice/internal/transcriber"
 	"github.com/spf13/cobra"
 )
 
@@ -29,8 +34,14 @@ func init() {
 	rootCmd.AddCommand(
 		serveCmd(),
 		toggleCmd(),
+		recordCmd(),
 		cancelCmd(),
+		pauseCmd(),
+		resumeCmd(),
 		statusCmd(),
+		levelCmd(),
+		devicesCmd(),
+		transcribeCmd(),
 		versionCmd(),
 		stopCmd(),
 		configureCmd(),
@@ -52,11 +63,13 @@ func serveCmd() *cobra.Command {
 }
 
 func toggleCmd() *cobra.Command {
-	return &cobra.Command{
+	var source string
+
+	cmd := &cobra.Command{
 		Use:   "toggle",
 		Short: "Toggle recording on/off",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			resp, err := bus.SendCommand('t')
+			resp, err := bus.SendCommandArg('t', source)
 			if err != nil {
 				return fmt.Errorf("failed to toggle recording: %w", err)
 			}
@@ -64,6 +77,51 @@ func toggleCmd() *cobra.Command {
 			return nil
 		},
 	}
+
+	cmd.Flags().StringVarP(&source, "source", "s", "", "Record \"mic\", \"monitor\" (system audio) or \"mixed\" instead of recording.source")
+	return cmd
+}
+
+func recordCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "record",
+		Short: "Start or stop recording explicitly (for push-to-talk binds)",
+		Long: `Start or stop recording explicitly instead of toggling.
+Both are idempotent: starting while already recording or stopping while idle
+does nothing, so a key press/release bind pair can never get out of sync.`,
+	}
+
+	var source string
+	startCmd := &cobra.Command{
+		Use:   "start",
+		Short: "Start recording unless already recording",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			resp, err := bus.SendCommandArg('b', source)
+			if err != nil {
+				return fmt.Errorf("failed to start recording: %w", err)
+			}
+			fmt.Print(resp)
+			return nil
+		},
+	}
+	startCmd.Flags().StringVarP(&source, "source", "s", "", "Record \"mic\", \"monitor\" (system audio) or \"mixed\" instead of recording.source")
+
+	cmd.AddCommand(
+		startCmd,
+		&cobra.Command{
+			Use:   "stop",
+			Short: "Stop recording and transcribe unless idle",
+			RunE: func(cmd *cobra.Command, args []string) error {
+				resp, err := bus.SendCommand('e')
+				if err != nil {
+					return fmt.Errorf("failed to stop recording: %w", err)
+				}
+				fmt.Print(resp)
+				return nil
+			},
+		},
+	)
+	return cmd
 }
 
 func statusCmd() *cobra.Command {
@@ -81,6 +139,149 @@ func statusCmd() *cobra.Command {
 	}
 }
 
+func levelCmd() *cobra.Command {
+	var watch bool
+
+	cmd := &cobra.Command{
+		Use:   "level",
+		Short: "Stream the live microphone level while recording",
+		Long: `Stream the live microphone input level while recording.
+Each line has the form "LEVEL rms=<0-1> peak=<0-1>", with zero levels and
+" status=paused" added while the recording is paused. The stream ends when the
+recording stops. With --watch the command keeps running across recordings and
+prints zero levels while idle, which suits waybar/eww widgets.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			for {
+				err := bus.StreamCommand('l', func(line string) bool {
+					if watch && strings.HasPrefix(line, "OK ") {
+						return true
+					}
+					fmt.Print(line)
+					return true
+				})
+				if err != nil {
+					return fmt.Errorf("failed to get level: %w", err)
+				}
+				if !watch {
+					return nil
+				}
+				fmt.Println("LEVEL rms=0.0000 peak=0.0000")
+				time.Sleep(200 * time.Millisecond)
+			}
+		},
+	}
+
+	cmd.Flags().BoolVarP(&watch, "watch", "w", false, "Keep streaming across recordings (zero levels while idle)")
+	return cmd
+}
+
+func devicesCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "devices",
+		Short: "List PipeWire capture devices usable as recording.device",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			devices, err := recording.ListDevices(cmd.Context())
+			if err != nil {
+				return fmt.Errorf("failed to list devices: %w", err)
+			}
+			if len(devices) == 0 {
+				fmt.Println("No capture devices found")
+				return nil
+			}
+
+			configured := ""
+			if cfg, err := config.Load(); err == nil {
+				configured = cfg.Recording.Device
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "DEFAULT\tNAME\tDESCRIPTION")
+			for _, d := range devices {
+				marker := ""
+				if d.Default {
+					marker = "*"
+				}
+				if configured != "" && (d.Name == configured || strconv.Itoa(d.ID) == configured) {
+					marker += " (configured)"
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\n", marker, d.Name, d.Description)
+			}
+			return w.Flush()
+		},
+	}
+}
+
+// defaultModels is used when transcribe switches provider without naming a model
+var defaultModels = map[string]string{
+	"openai":                "whisper-1",
+	"groq-transcription":    "whisper-large-v3",
+	"groq-translation":      "whisper-large-v3",
+	"mistral-transcription": "voxtral-mini-latest",
+}
+
+func transcribeCmd() *cobra.Command {
+	var provider, model, language string
+
+	cmd := &cobra.Command{
+		Use:   "transcribe <file>",
+		Short: "Transcribe an audio file and print the text",
+		Long: `Transcribe an existing audio file with the configured provider and print the
+text to stdout. WAV files are read directly; FLAC, MP3 and other formats are
+decoded with ffmpeg when it is installed. The daemon does not need to be running.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+
+			if provider != "" && (provider != cfg.Transcription.Provider || len(cfg.Transcription.Providers) > 0) {
+				cfg.Transcription.Provider = provider
+				// An explicit provider replaces the fallback chain
+				cfg.Transcription.Providers = nil
+				// The configured key belongs to the configured provider; use the environment instead
+				cfg.Transcription.APIKey = ""
+				if model == "" {
+					cfg.Transcription.Model = defaultModels[provider]
+				}
+			}
+			if model != "" {
+				cfg.Transcription.Model = model
+				if len(cfg.Transcription.Providers) > 0 {
+					cfg.Transcription.Providers[0].Model = model
+				}
+			}
+			if cmd.Flags().Changed("language") {
+				cfg.Transcription.Language = language
+			}
+			// Files do not need the recording setup, only the provider
+			if err := cfg.ValidateTranscription(); err != nil {
+				return fmt.Errorf("invalid configuration: %w", err)
+			}
+
+			transCfg := cfg.ToTranscriberConfig()
+			if serverConfig, ok := cfg.ToWhisperServerConfig(); ok {
+				// Uses the daemon's server when it is up, otherwise runs one for this file
+				server := transcriber.NewWhisperServer(serverConfig)
+				defer server.Stop()
+				transCfg.UseServer(server)
+			}
+
+			text, err := transcriber.TranscribeFile(cmd.Context(), transCfg, args[0])
+			if err != nil {
+				return fmt.Errorf("failed to transcribe %s: %w", args[0], err)
+			}
+			fmt.Println(text)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&provider, "provider", "p", "", "Transcription provider (default from config)")
+	cmd.Flags().StringVarP(&model, "model", "m", "", "Model (default from config, or the provider's default with --provider)")
+	cmd.Flags().StringVarP(&language, "language", "l", "", "Language code, empty for auto-detect (default from config)")
+	return cmd
+}
+
 func versionCmd() *cobra.Command {
 	return &cobra.Command{
 		Use:   "version",
@@ -126,13 +327,43 @@ func cancelCmd() *cobra.Command {
 	}
 }
 
+func pauseCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "pause",
+		Short: "Pause the current recording without ending it",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			resp, err := bus.SendCommand('p')
+			if err != nil {
+				return fmt.Errorf("failed to pause recording: %w", err)
+			}
+			fmt.Print(resp)
+			return nil
+		},
+	}
+}
+
+func resumeCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "resume",
+		Short: "Resume a paused recording",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			resp, err := bus.SendCommand('r')
+			if err != nil {
+				return fmt.Errorf("failed to resume recording: %w", err)
+			}
+			fmt.Print(resp)
+			return nil
+		},
+	}
+}
+
 func configureCmd() *cobra.Command {
 	return &cobra.Command{
 		Use:   "configure",
 		Short: "Interactive configuration setup",
 		Long: `Interactive configuration wizard for hyprvoice.
 This will guide you through setting up:
-- Transcription provider (OpenAI, Groq, or Mistral)
+- Transcription provider (OpenAI, Groq, Mistral, or a local server)
 - API keys and model selection
 - Audio and text injection preferences
 - Notification settings`,
@@ -159,6 +390,12 @@ func runInteractiveConfig() error {
 	fmt.Println("📝 Transcription Configuration")
 	fmt.Println("------------------------------")
 
+	if len(cfg.Transcription.Providers) > 0 {
+		fmt.Printf("Note: a fallback chain of %d providers is set in the config file and is used instead of the provider below.\n", len(cfg.Transcription.Providers))
+		fmt.Println("      It is kept as it is; edit [[transcription.providers]] to change it.")
+		fmt.Println()
+	}
+
 	// Provider selection
 	for {
 		fmt.Println("Select transcription provider:")
@@ -167,7 +404,9 @@ func runInteractiveConfig() error {
 		fmt.Println("  3. groq-translation      - Groq Whisper API (translate to English)")
 		fmt.Println("  4. mistral-transcription - Mistral Voxtral API (excellent for European languages)")
 		fmt.Println("  5. whisper-cpp           - Local whisper.cpp server")
-		fmt.Printf("Provider [1-5] (current: %s): ", cfg.Transcription.Provider)
+		fmt.Println("  6. openai-compatible     - Any server with the OpenAI audio API")
+		fmt.Println("  7. whisper-cli           - Local whisper.cpp, offline without a server")
+		fmt.Printf("Provider [1-7] (current: %s): ", cfg.Transcription.Provider)
 		if !scanner.Scan() {
 			break
 		}
@@ -186,10 +425,14 @@ func runInteractiveConfig() error {
 			cfg.Transcription.Provider = "mistral-transcription"
 		case "5":
 			cfg.Transcription.Provider = "whisper-cpp"
-		case "openai", "groq-transcription", "groq-translation", "mistral-transcription", "whisper-cpp":
+		case "6":
+			cfg.Transcription.Provider = "openai-compatible"
+		case "7":
+			cfg.Transcription.Provider = "whisper-cli"
+		case "openai", "groq-transcription", "groq-translation", "mistral-transcription", "whisper-cpp", "openai-compatible", "whisper-cli":
 			cfg.Transcription.Provider = input
 		default:
-			fmt.Println("❌ Error: invalid provider. Please enter 1-5 or provider name.")
+			fmt.Println("❌ Error: invalid provider. Please enter 1-7 or provider name.")
 			fmt.Println()
 			continue
 		}
@@ -289,10 +532,53 @@ func runInteractiveConfig() error {
 		}
 		// No model needed for whisper-cpp - it uses whatever model is loaded on the server
 		cfg.Transcription.Model = ""
+	case "openai-compatible":
+		fmt.Println("\nOpenAI-Compatible Server:")
+		fmt.Printf("Base URL (current: %s): ", cfg.Transcription.BaseURL)
+		if scanner.Scan() {
+			input := strings.TrimSpace(scanner.Text())
+			if input != "" {
+				cfg.Transcription.BaseURL = input
+			}
+		}
+		fmt.Printf("Model, as named by the server (current: %s): ", cfg.Transcription.Model)
+		if scanner.Scan() {
+			input := strings.TrimSpace(scanner.Text())
+			if input != "" {
+				cfg.Transcription.Model = input
+			}
+		}
+		for {
+			fmt.Printf("Endpoint [transcription/translation] (current: %s): ", cfg.Transcription.Endpoint)
+			if !scanner.Scan() {
+				break
+			}
+			input := strings.TrimSpace(scanner.Text())
+			switch input {
+			case "":
+			case transcriber.EndpointTranscription, transcriber.EndpointTranslation:
+				cfg.Transcription.Endpoint = input
+			default:
+				fmt.Println("❌ Error: invalid endpoint. Please enter transcription or translation.")
+				continue
+			}
+			break
+		}
+	case "whisper-cli":
+		fmt.Println("\nWhisper.cpp Local Model:")
+		fmt.Printf("ggml model path (current: %s): ", cfg.Transcription.ModelPath)
+		if scanner.Scan() {
+			input := strings.TrimSpace(scanner.Text())
+			if input != "" {
+				cfg.Transcription.ModelPath = input
+			}
+		}
+		// The model file is the model; there is no model name to pick
+		cfg.Transcription.Model = ""
 	}
 
-	// API Key (provider-aware) - not needed for whisper-cpp
-	if cfg.Transcription.Provider != "whisper-cpp" {
+	// API Key (provider-aware) - not needed for local whisper.cpp
+	if cfg.Transcription.Provider != "whisper-cpp" && cfg.Transcription.Provider != "whisper-cli" {
 		var envVarName string
 		switch cfg.Transcription.Provider {
 		case "openai":
@@ -302,7 +588,11 @@ func runInteractiveConfig() error {
 		default:
 			envVarName = "GROQ_API_KEY"
 		}
-		fmt.Printf("\nAPI Key (current: %s, leave empty to use %s env var): ", maskAPIKey(cfg.Transcription.APIKey), envVarName)
+		if cfg.Transcription.Provider == "openai-compatible" {
+			fmt.Printf("\nAPI Key (current: %s, leave empty if the server needs none): ", maskAPIKey(cfg.Transcription.APIKey))
+		} else {
+			fmt.Printf("\nAPI Key (current: %s, leave empty to use %s env var): ", maskAPIKey(cfg.Transcription.APIKey), envVarName)
+		}
 		if scanner.Scan() {
 			input := strings.TrimSpace(scanner.Text())
 			if input != "" {
@@ -310,7 +600,7 @@ func runInteractiveConfig() error {
 			}
 		}
 	} else {
-		// Clear API key for whisper-cpp
+		// Clear API key for local whisper.cpp
 		cfg.Transcription.APIKey = ""
 	}
 
@@ -526,6 +816,50 @@ func runInteractiveConfig() error {
 		break
 	}
 
+	// Microphone selection (PipeWire only, skipped when PipeWire cannot be queried)
+	if len(cfg.Recording.Backends) > 0 && cfg.Recording.Backends[0] != "pw-record" {
+		fmt.Printf("\nMicrophone listing needs the pw-record backend, keeping device: %q\n", cfg.Recording.Device)
+	} else if devices, err := recording.ListDevices(context.Background()); err != nil {
+		fmt.Printf("\n⚠️  Could not list microphones (%v), keeping device: %q\n", err, cfg.Recording.Device)
+	} else if len(devices) > 0 {
+		for {
+			fmt.Println("\nMicrophone:")
+			fmt.Println("  0. (system default)")
+			for i, d := range devices {
+				marker := ""
+				if d.Default {
+					marker = " [default]"
+				}
+				fmt.Printf("  %d. %s - %s%s\n", i+1, d.Name, d.Description, marker)
+			}
+			current := cfg.Recording.Device
+			if current == "" {
+				current = "system default"
+			}
+			fmt.Printf("Device [0-%d] (current: %s): ", len(devices), current)
+			if !scanner.Scan() {
+				break
+			}
+			input := strings.TrimSpace(scanner.Text())
+			if input == "" {
+				break // keep current
+			}
+			if n, err := strconv.Atoi(input); err == nil && n >= 0 && n <= len(devices) {
+				if n == 0 {
+					cfg.Recording.Device = ""
+				} else {
+					cfg.Recording.Device = devices[n-1].Name
+				}
+				break
+			}
+			if d, ok := recording.FindDevice(devices, input); ok {
+				cfg.Recording.Device = d.Name
+				break
+			}
+			fmt.Printf("❌ Error: unknown device. Please enter 0-%d or a device name.\n", len(devices))
+		}
+	}
+
 	fmt.Println()
 
 	// Validate configuration
@@ -534,6 +868,12 @@ func runInteractiveConfig() error {
 		fmt.Println("Please check your inputs and try again.")
 		return err
 	}
+	if len(cfg.Recording.FallbackDevices) > 0 {
+		if err := cfg.CheckDevice(); err != nil {
+			fmt.Printf("⚠️  Warning: %v\n", err)
+			fmt.Println("   Recording uses fallback_devices while it is unplugged.")
+		}
+	}
 
 	// Save configuration
 	fmt.Println("💾 Saving configuration...")
@@ -589,6 +929,56 @@ func formatBackends(backends []string) string {
 	return strings.Join(quoted, ", ")
 }
 
+// formatProviders writes the fallback chain as [[transcription.providers]]
+// blocks, leaving out settings an entry does not set
+func formatProviders(providers []config.ProviderConfig) string {
+	var b strings.Builder
+	for _, p := range providers {
+		b.WriteString("\n\n  [[transcription.providers]]")
+		fmt.Fprintf(&b, "\n    provider = %q", p.Provider)
+		if p.APIKey != "" {
+			fmt.Fprintf(&b, "\n    api_key = %q", p.APIKey)
+		}
+		if p.Model != "" {
+			fmt.Fprintf(&b, "\n    model = %q", p.Model)
+		}
+		if p.ServerURL != "" {
+			fmt.Fprintf(&b, "\n    server_url = %q", p.ServerURL)
+		}
+		if p.BaseURL != "" {
+			fmt.Fprintf(&b, "\n    base_url = %q", p.BaseURL)
+		}
+		if len(p.Headers) > 0 {
+			fmt.Fprintf(&b, "\n    headers = {%s}", formatHeaders(p.Headers))
+		}
+		if p.Endpoint != "" {
+			fmt.Fprintf(&b, "\n    endpoint = %q", p.Endpoint)
+		}
+		if p.UploadFormat != "" {
+			fmt.Fprintf(&b, "\n    upload_format = %q", p.UploadFormat)
+		}
+	}
+	return b.String()
+}
+
+// formatHeaders writes headers as the inside of a TOML inline table
+func formatHeaders(headers map[string]string) string {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = fmt.Sprintf("%q = %q", name, headers[name])
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return " " + strings.Join(pairs, ", ") + " "
+}
+
 func maskAPIKey(key string) string {
 	if key == "" {
 		return "<not set>"
@@ -617,21 +1007,59 @@ func saveConfig(cfg *config.Config) error {
 
 # Audio Recording Configuration
 [recording]
+  backends = [%s]     # Ordered capture fallback chain: "pw-record", "parecord", "arecord", "ffmpeg"
   sample_rate = %d          # Audio sample rate in Hz (16000 recommended for speech)
   channels = %d                 # Number of audio channels (1 = mono, 2 = stereo)
-  format = "%s"               # Audio format (s16 = 16-bit signed integers)
+  format = "%s"               # Audio format: "s16", "s24", "s32" or "f32"
   buffer_size = %d           # Internal buffer size in bytes (larger = less CPU, more latency)
-  device = "%s"                  # PipeWire audio device (empty = use default microphone)
+  device = "%s"                  # Capture device (empty = default microphone, see 'hyprvoice devices')
+  fallback_devices = [%s]        # Devices to switch to, in order, if the device is unplugged mid-recording (then the default)
+  source = "%s"               # What to record: "mic", "monitor" (system audio) or "mixed" (both)
+  monitor_device = "%s"          # Sink whose output monitor/mixed records (empty = default output)
   channel_buffer_size = %d     # Audio frame buffer size (frames to buffer)
   timeout = "%s"               # Maximum recording duration (e.g., "30s", "2m", "5m")
+  timeout_warning = "%s"      # Notify this long before the timeout is reached (0 = no warning)
+  timeout_action = "%s" # At the timeout: "transcribe" what was recorded, or "abort" and discard it
+  vad_enabled = %v          # Stop recording automatically after trailing silence (one keypress dictation)
+  vad_silence_duration = "%s" # Silence required after speech before auto-stop
+  vad_threshold = %g         # Energy threshold (0.0-1.0) separating speech from silence
+  vad_min_speech = "%s"     # Minimum speech before silence can end the recording
+  warm = %v                 # Keep the microphone open between recordings so the first syllable is never lost
+  pre_roll = "%s"           # Audio from before the toggle included in each recording (warm mode only, 0 = none)
+  dc_removal = %v            # Remove DC offset from cheap microphones
+  highpass_cutoff = %.1f       # High-pass filter in Hz against fan hum and rumble (0 = disabled)
+  normalize = %v             # Automatic gain normalization for quiet microphones
+  normalize_target = %g       # Target speech level (0.0-1.0 RMS)
+  normalize_max_gain = %.1f    # Maximum gain applied to quiet input (10 = +20 dB)
+  noise_gate = %v           # Mute audio below noise_gate_threshold between words
+  noise_gate_threshold = %g  # Level (0.0-1.0 RMS) below which the noise gate closes
+  trim_silence = %v          # Cut leading/trailing silence and shorten long pauses before upload
+  trim_threshold = %g        # Level (0.0-1.0 RMS) below which audio counts as silence
+  max_pause = "%s"             # Longest pause kept between words
 
 # Speech Transcription Configuration
 [transcription]
-  provider = "%s"          # Transcription service: "openai", "groq-transcription", "groq-translation", "mistral-transcription", or "whisper-cpp"
+  provider = "%s"          # Transcription service: "openai", "groq-transcription", "groq-translation", "mistral-transcription", "whisper-cpp", "whisper-cli", or "openai-compatible"
   api_key = "%s"                 # API key (or set OPENAI_API_KEY/GROQ_API_KEY/MISTRAL_API_KEY environment variable)
   language = "%s"                # Language code (empty for auto-detect, "en", "it", "es", "fr", etc.)
-  model = "%s"          # Model: OpenAI="whisper-1", Groq="whisper-large-v3", Mistral="voxtral-mini-latest" (not needed for whisper-cpp)
+  model = "%s"          # Model: OpenAI="whisper-1", Groq="whisper-large-v3", Mistral="voxtral-mini-latest" (not needed for whisper-cpp/whisper-cli)
   server_url = "%s"              # For whisper-cpp only: local server URL (e.g., "http://192.168.10.37:8025/inference")
+  base_url = "%s"                # For openai-compatible only: API root (e.g., "http://localhost:8000/v1")
+  headers = {%s}                 # For openai-compatible only: extra HTTP headers (e.g., { "X-Api-Key" = "..." })
+  endpoint = "%s"   # For openai-compatible only: "transcription" or "translation" (to English)
+  model_path = "%s"              # For whisper-cli or a managed server: ggml model file (e.g., "~/models/ggml-base.en.bin")
+  whisper_cli = "%s"  # For whisper-cli only: whisper.cpp command line binary
+  threads = %d                  # For whisper-cli or a managed server: CPU threads (0 = whisper.cpp default)
+  translate = %v            # For whisper-cli only: translate speech to English text
+  manage_server = %v        # For whisper-cpp only: run whisper-server with model_path on server_port instead of using server_url
+  whisper_server = "%s" # For a managed server: whisper.cpp server binary
+  server_port = %d           # For a managed server: port on 127.0.0.1
+  server_idle_timeout = "%s" # For a managed server: stop it after this long unused (0 = keep running)
+  upload_format = "%s"           # Audio sent to the provider: "wav", "flac" or "opus" (needs ffmpeg); empty uses wav (whisper.cpp always gets wav)
+  max_upload_mb = %d            # Longer recordings are split at pauses into requests below this size
+  chunk_concurrency = %d          # Chunks transcribed at once (1 = in order, each using the previous text as context)
+  streaming = %v            # Transcribe while you talk, segment by segment, so only the last one is left at stop
+  streaming_pause = "%s"    # Silence that ends a segment in streaming mode%s
 
 # Text Injection Configuration
 [injection]
@@ -639,7 +1067,22 @@ func saveConfig(cfg *config.Config) error {
   ydotool_timeout = "%s"       # Timeout for ydotool commands
   wtype_timeout = "%s"         # Timeout for wtype commands
   clipboard_timeout = "%s"     # Timeout for clipboard operations
+  live = %v                 # Type text segment by segment while you talk (uses streaming, needs ydotool or wtype)
 
+# Session Archive Configuration (keeps audio and transcripts for debugging)
+[archive]
+  enabled = %v              # Save each session as WAV plus a JSON sidecar
+  dir = "%s"                     # Archive directory (empty = ~/.local/share/hyprvoice/recordings)
+  max_count = %d              # Keep at most this many sessions (0 = unlimited)
+  max_age = "%s"             # Delete sessions older than this (0 = keep forever)
+  max_size_mb = %d            # Total archive size limit in MB (0 = unlimited)
+
+# Capture backend explanations:
+# - "pw-record": PipeWire (default). device is a PipeWire node name from 'hyprvoice devices'.
+# - "parecord": PulseAudio (or pipewire-pulse). device is a PulseAudio source name (pactl list short sources).
+# - "arecord": ALSA only, works without a sound server. device is an ALSA name like "hw:0" or "default".
+# - "ffmpeg": Captures via PulseAudio, or ALSA when device starts with "hw:"/"plughw:".
+#
 # Backend explanations:
 # - "ydotool": Uses ydotool (requires ydotoold daemon running). Most compatible with Chromium/Electron apps.
 # - "wtype": Uses wtype for Wayland. May have issues with some Chromium-based apps.
@@ -657,6 +1100,11 @@ func saveConfig(cfg *config.Config) error {
 #     Models: voxtral-mini-latest or voxtral-mini-2507
 # - "whisper-cpp": Local whisper.cpp server (requires server_url, no API key needed)
 #     Set server_url to your local server endpoint (e.g., "http://192.168.10.37:8025/inference")
+#     Or set manage_server = true and model_path to have the daemon start and stop whisper-server itself
+# - "whisper-cli": Runs whisper.cpp's whisper-cli directly, fully offline (requires model_path, no server or API key)
+#     Download ggml models from https://huggingface.co/ggerganov/whisper.cpp
+# - "openai-compatible": Any server with the OpenAI audio API (LocalAI, faster-whisper-server, vLLM, proxies)
+#     Requires base_url; model is whatever the server serves; api_key and headers are optional
 #
 # Language codes: Use empty string ("") for automatic detection, or specific codes like:
 # "en" (English), "it" (Italian), "es" (Spanish), "fr" (French), "de" (German), etc.
@@ -667,22 +1115,67 @@ func saveConfig(cfg *config.Config) error {
   enabled = %v               # Enable desktop notifications
   type = "%s"             # Notification type ("desktop", "log", "none")
 `,
+		formatBackends(cfg.Recording.Backends),
 		cfg.Recording.SampleRate,
 		cfg.Recording.Channels,
 		cfg.Recording.Format,
 		cfg.Recording.BufferSize,
 		cfg.Recording.Device,
+		formatBackends(cfg.Recording.FallbackDevices),
+		cfg.Recording.Source,
+		cfg.Recording.MonitorDevice,
 		cfg.Recording.ChannelBufferSize,
 		cfg.Recording.Timeout,
+		cfg.Recording.TimeoutWarning,
+		cfg.Recording.TimeoutAction,
+		cfg.Recording.VADEnabled,
+		cfg.Recording.VADSilenceDuration,
+		cfg.Recording.VADThreshold,
+		cfg.Recording.VADMinSpeech,
+		cfg.Recording.Warm,
+		cfg.Recording.PreRoll,
+		cfg.Recording.DCRemoval,
+		cfg.Recording.HighPassCutoff,
+		cfg.Recording.Normalize,
+		cfg.Recording.NormalizeTarget,
+		cfg.Recording.NormalizeMaxGain,
+		cfg.Recording.NoiseGate,
+		cfg.Recording.NoiseGateThreshold,
+		cfg.Recording.TrimSilence,
+		cfg.Recording.TrimThreshold,
+		cfg.Recording.MaxPause,
 		cfg.Transcription.Provider,
 		cfg.Transcription.APIKey,
 		cfg.Transcription.Language,
 		cfg.Transcription.Model,
 		cfg.Transcription.ServerURL,
+		cfg.Transcription.BaseURL,
+		formatHeaders(cfg.Transcription.Headers),
+		cfg.Transcription.Endpoint,
+		cfg.Transcription.ModelPath,
+		cfg.Transcription.WhisperCLI,
+		cfg.Transcription.Threads,
+		cfg.Transcription.Translate,
+		cfg.Transcription.ManageServer,
+		cfg.Transcription.WhisperServer,
+		cfg.Transcription.ServerPort,
+		cfg.Transcription.ServerIdleTimeout,
+		cfg.Transcription.UploadFormat,
+		cfg.Transcription.MaxUploadMB,
+		cfg.Transcription.ChunkConcurrency,
+		cfg.Transcription.Streaming,
+		cfg.Transcription.StreamingPause,
+		formatProviders(cfg.Transcription.Providers),
 		formatBackends(cfg.Injection.Backends),
 		cfg.Injection.YdotoolTimeout,
 		cfg.Injection.WtypeTimeout,
 		cfg.Injection.ClipboardTimeout,
+		cfg.Injection.Live,
+		cfg.Archive.Enabled,
+		cfg.Archive.Dir,
+		cfg.Archive.MaxCount,
+		cfg.Archive.MaxAge,
+		cfg.Archive.MaxSizeMB,
 		cfg.Notifications.Enabled,
 		cfg.Notifications.Type,
 	)
@@ -719,6 +1212,26 @@ func saveConfig(cfg *config.Config) error {
 			messagesContent += fmt.Sprintf("    [notifications.messages.injection_aborted]\n      body = %q\n",
 				msgs.InjectionAborted.Body)
 		}
+		if msgs.RecordingPaused.Title != "" || msgs.RecordingPaused.Body != "" {
+			messagesContent += fmt.Sprintf("    [notifications.messages.recording_paused]\n      title = %q\n      body = %q\n",
+				msgs.RecordingPaused.Title, msgs.RecordingPaused.Body)
+		}
+		if msgs.RecordingResumed.Title != "" || msgs.RecordingResumed.Body != "" {
+			messagesContent += fmt.Sprintf("    [notifications.messages.recording_resumed]\n      title = %q\n      body = %q\n",
+				msgs.RecordingResumed.Title, msgs.RecordingResumed.Body)
+		}
+		if msgs.TimeoutWarning.Title != "" || msgs.TimeoutWarning.Body != "" {
+			messagesContent += fmt.Sprintf("    [notifications.messages.timeout_warning]\n      title = %q\n      body = %q\n",
+				msgs.TimeoutWarning.Title, msgs.TimeoutWarning.Body)
+		}
+		if msgs.DeviceSwitched.Title != "" || msgs.DeviceSwitched.Body != "" {
+			messagesContent += fmt.Sprintf("    [notifications.messages.device_switched]\n      title = %q\n      body = %q\n",
+				msgs.DeviceSwitched.Title, msgs.DeviceSwitched.Body)
+		}
+		if msgs.ProviderFallback.Title != "" || msgs.ProviderFallback.Body != "" {
+			messagesContent += fmt.Sprintf("    [notifications.messages.provider_fallback]\n      title = %q\n      body = %q\n",
+				msgs.ProviderFallback.Title, msgs.ProviderFallback.Body)
+		}
 		if _, err := file.WriteString(messagesContent); err != nil {
 			return fmt.Errorf("failed to write messages config: %w", err)
 		}
@@ -733,5 +1246,10 @@ func hasCustomMessages(msgs config.MessagesConfig) bool {
 		msgs.ConfigReloaded.Title != "" || msgs.ConfigReloaded.Body != "" ||
 		msgs.OperationCancelled.Title != "" || msgs.OperationCancelled.Body != "" ||
 		msgs.RecordingAborted.Body != "" ||
-		msgs.InjectionAborted.Body != ""
+		msgs.InjectionAborted.Body != "" ||
+		msgs.RecordingPaused.Title != "" || msgs.RecordingPaused.Body != "" ||
+		msgs.RecordingResumed.Title != "" || msgs.RecordingResumed.Body != "" ||
+		msgs.TimeoutWarning.Title != "" || msgs.TimeoutWarning.Body != "" ||
+		msgs.DeviceSwitched.Title != "" || msgs.DeviceSwitched.Body != "" ||
+		msgs.ProviderFallback.Title != "" || msgs.ProviderFallback.Body != ""
 }
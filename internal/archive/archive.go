@@ -0,0 +1,258 @@
+package archive
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/leonardotrapani/hyprvoice/internal/recording"
+)
+
+// Config controls where sessions are archived and how long they are kept.
+// Zero limits are not enforced.
+type Config struct {
+	Dir      string
+	MaxCount int
+	MaxAge   time.Duration
+	MaxSize  int64 // bytes across all archived files
+}
+
+// Session is one finished recording together with what became of it
+type Session struct {
+	StartedAt time.Time
+	Audio     []byte
+	// Audio layout after conversion to the provider format
+	SampleRate int
+	Channels   int
+	Format     string
+
+	Provider   string
+	Model      string
+	Language   string
+	Transcript string
+	Error      string
+
+	RecordingDuration     time.Duration
+	TranscriptionDuration time.Duration
+	InjectionDuration     time.Duration
+}
+
+// Metadata is the JSON sidecar written next to each archived WAV file
+type Metadata struct {
+	ID         string    `json:"id"`
+	Audio      string    `json:"audio"`
+	StartedAt  time.Time `json:"started_at"`
+	Provider   string    `json:"provider"`
+	Model      string    `json:"model"`
+	Language   string    `json:"language,omitempty"`
+	Transcript string    `json:"transcript"`
+	Error      string    `json:"error,omitempty"`
+	SampleRate int       `json:"sample_rate"`
+	Channels   int       `json:"channels"`
+	Format     string    `json:"format"`
+	Timings    Timings   `json:"timings"`
+}
+
+type Timings struct {
+	AudioSeconds         float64 `json:"audio_seconds"`
+	RecordingSeconds     float64 `json:"recording_seconds"`
+	TranscriptionSeconds float64 `json:"transcription_seconds"`
+	InjectionSeconds     float64 `json:"injection_seconds"`
+}
+
+// sessionID matches the file names Save generates, so retention never touches
+// other files in a user-chosen directory
+var sessionID = regexp.MustCompile(`^\d{8}-\d{6}-\d{3}$`)
+
+type Archive struct {
+	config Config
+	mu     sync.Mutex // serializes saving and pruning
+}
+
+func New(config Config) *Archive {
+	return &Archive{config: config}
+}
+
+// DefaultDir returns the archive location under the XDG data directory
+func DefaultDir() (string, error) {
+	dataDir := os.Getenv("XDG_DATA_HOME")
+	if dataDir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to get home directory: %w", err)
+		}
+		dataDir = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(dataDir, "hyprvoice", "recordings"), nil
+}
+
+// Save writes the session audio and its metadata sidecar, then applies the
+// retention limits. It returns the path of the WAV file.
+func (a *Archive) Save(session Session) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if err := os.MkdirAll(a.config.Dir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create archive directory: %w", err)
+	}
+
+	id := strings.ReplaceAll(session.StartedAt.Format("20060102-150405.000"), ".", "-")
+	wavPath := filepath.Join(a.config.Dir, id+".wav")
+	wav := recording.EncodeWAV(session.Audio, session.SampleRate, session.Channels, session.Format)
+	if err := os.WriteFile(wavPath, wav, 0600); err != nil {
+		return "", fmt.Errorf("failed to write archive audio: %w", err)
+	}
+
+	var audioSeconds float64
+	if bytesPerSecond := session.SampleRate * session.Channels * recording.SampleSize(session.Format); bytesPerSecond > 0 {
+		audioSeconds = float64(len(session.Audio)) / float64(bytesPerSecond)
+	}
+
+	meta := Metadata{
+		ID:         id,
+		Audio:      filepath.Base(wavPath),
+		StartedAt:  session.StartedAt,
+		Provider:   session.Provider,
+		Model:      session.Model,
+		Language:   session.Language,
+		Transcript: session.Transcript,
+		Error:      session.Error,
+		SampleRate: session.SampleRate,
+		Channels:   session.Channels,
+		Format:     session.Format,
+		Timings: Timings{
+			AudioSeconds:         audioSeconds,
+			RecordingSeconds:     session.RecordingDuration.Seconds(),
+			TranscriptionSeconds: session.TranscriptionDuration.Seconds(),
+			InjectionSeconds:     session.InjectionDuration.Seconds(),
+		},
+	}
+	data, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode archive metadata: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(a.config.Dir, id+".json"), append(data, '\n'), 0600); err != nil {
+		return "", fmt.Errorf("failed to write archive metadata: %w", err)
+	}
+
+	if err := a.prune(time.Now()); err != nil {
+		log.Printf("Archive: retention failed: %v", err)
+	}
+
+	return wavPath, nil
+}
+
+// Prune removes the oldest sessions until the retention limits are met
+func (a *Archive) Prune() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.prune(time.Now())
+}
+
+// entry is one archived session and the files that belong to it
+type entry struct {
+	id      string
+	files   []string
+	size    int64
+	modTime time.Time
+	wav     bool
+	sidecar bool
+}
+
+func (a *Archive) prune(now time.Time) error {
+	entries, err := a.list()
+	if err != nil {
+		return err
+	}
+
+	var total int64
+	for _, e := range entries {
+		total += e.size
+	}
+
+	// entries are sorted oldest first
+	removed := 0
+	for i, e := range entries {
+		remaining := len(entries) - i
+		expired := a.config.MaxAge > 0 && now.Sub(e.modTime) > a.config.MaxAge
+		tooMany := a.config.MaxCount > 0 && remaining > a.config.MaxCount
+		tooBig := a.config.MaxSize > 0 && total > a.config.MaxSize
+		if !expired && !tooMany && !tooBig {
+			break
+		}
+
+		for _, file := range e.files {
+			if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to remove %s: %w", file, err)
+			}
+		}
+		total -= e.size
+		removed++
+	}
+
+	if removed > 0 {
+		log.Printf("Archive: removed %d old sessions", removed)
+	}
+	return nil
+}
+
+func (a *Archive) list() ([]entry, error) {
+	dirEntries, err := os.ReadDir(a.config.Dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive directory: %w", err)
+	}
+
+	byID := make(map[string]*entry)
+	for _, de := range dirEntries {
+		ext := filepath.Ext(de.Name())
+		if de.IsDir() || (ext != ".wav" && ext != ".json") {
+			continue
+		}
+		info, err := de.Info()
+		if err != nil {
+			continue
+		}
+
+		id := strings.TrimSuffix(de.Name(), ext)
+		if !sessionID.MatchString(id) {
+			continue
+		}
+		e, ok := byID[id]
+		if !ok {
+			e = &entry{id: id}
+			byID[id] = e
+		}
+		if ext == ".wav" {
+			e.wav = true
+		} else {
+			e.sidecar = true
+		}
+		e.files = append(e.files, filepath.Join(a.config.Dir, de.Name()))
+		e.size += info.Size()
+		if info.ModTime().After(e.modTime) {
+			e.modTime = info.ModTime()
+		}
+	}
+
+	// A session is only counted once both its WAV and sidecar exist
+	entries := make([]entry, 0, len(byID))
+	for _, e := range byID {
+		if e.wav && e.sidecar {
+			entries = append(entries, *e)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].id < entries[j].id
+	})
+	return entries, nil
+}
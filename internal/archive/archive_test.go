@@ -0,0 +1,149 @@
+package archive
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testSession(startedAt time.Time) Session {
+	return Session{
+		StartedAt:             startedAt,
+		Audio:                 make([]byte, 32000), // one second of 16 kHz mono s16
+		SampleRate:            16000,
+		Channels:              1,
+		Format:                "s16",
+		Provider:              "openai",
+		Model:                 "whisper-1",
+		Transcript:            "hello world",
+		RecordingDuration:     1500 * time.Millisecond,
+		TranscriptionDuration: 800 * time.Millisecond,
+	}
+}
+
+func TestArchive_Save(t *testing.T) {
+	dir := t.TempDir()
+	a := New(Config{Dir: dir})
+
+	startedAt := time.Date(2025, 3, 1, 12, 30, 45, 123000000, time.UTC)
+	wavPath, err := a.Save(testSession(startedAt))
+	if err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	wav, err := os.ReadFile(wavPath)
+	if err != nil {
+		t.Fatalf("failed to read archived audio: %v", err)
+	}
+	if string(wav[:4]) != "RIFF" || len(wav) != 44+32000 {
+		t.Errorf("archived audio is not a WAV file of the expected size (%d bytes)", len(wav))
+	}
+
+	data, err := os.ReadFile(strings.TrimSuffix(wavPath, ".wav") + ".json")
+	if err != nil {
+		t.Fatalf("failed to read sidecar: %v", err)
+	}
+	var meta Metadata
+	if err := json.Unmarshal(data, &meta); err != nil {
+		t.Fatalf("sidecar is not valid JSON: %v", err)
+	}
+
+	if meta.ID != "20250301-123045-123" {
+		t.Errorf("ID = %q, want %q", meta.ID, "20250301-123045-123")
+	}
+	if meta.Audio != filepath.Base(wavPath) {
+		t.Errorf("Audio = %q, want %q", meta.Audio, filepath.Base(wavPath))
+	}
+	if meta.Provider != "openai" || meta.Model != "whisper-1" || meta.Transcript != "hello world" {
+		t.Errorf("metadata = %+v, missing provider, model or transcript", meta)
+	}
+	if meta.Timings.AudioSeconds != 1 || meta.Timings.RecordingSeconds != 1.5 || meta.Timings.TranscriptionSeconds != 0.8 {
+		t.Errorf("Timings = %+v", meta.Timings)
+	}
+}
+
+func TestArchive_Prune(t *testing.T) {
+	base := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		config    Config
+		now       time.Time
+		wantKept  int
+		wantFirst string
+	}{
+		{"no limits", Config{}, base, 5, "20250301-120000-000"},
+		{"max count", Config{MaxCount: 2}, base, 2, "20250301-120300-000"},
+		{"max size", Config{MaxSize: 3 * (32044 + 1000)}, base, 3, "20250301-120200-000"},
+		{"max age", Config{MaxAge: time.Hour}, time.Now().Add(2 * time.Hour), 0, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			tt.config.Dir = dir
+			writer := New(Config{Dir: dir})
+			for i := 0; i < 5; i++ {
+				if _, err := writer.Save(testSession(base.Add(time.Duration(i) * time.Minute))); err != nil {
+					t.Fatalf("Save() error = %v", err)
+				}
+			}
+
+			a := New(tt.config)
+			if err := a.prune(tt.now); err != nil {
+				t.Fatalf("prune() error = %v", err)
+			}
+
+			entries, err := a.list()
+			if err != nil {
+				t.Fatalf("list() error = %v", err)
+			}
+			if len(entries) != tt.wantKept {
+				t.Fatalf("kept %d sessions, want %d", len(entries), tt.wantKept)
+			}
+			if tt.wantKept > 0 && entries[0].id != tt.wantFirst {
+				t.Errorf("oldest kept session = %s, want %s", entries[0].id, tt.wantFirst)
+			}
+			for _, e := range entries {
+				if len(e.files) != 2 {
+					t.Errorf("session %s has %d files, want WAV and sidecar", e.id, len(e.files))
+				}
+			}
+		})
+	}
+}
+
+func TestArchive_PruneMissingDir(t *testing.T) {
+	a := New(Config{Dir: filepath.Join(t.TempDir(), "missing"), MaxCount: 1})
+	if err := a.Prune(); err != nil {
+		t.Errorf("Prune() error = %v, want nil for a missing directory", err)
+	}
+}
+
+func TestArchive_PruneKeepsForeignFiles(t *testing.T) {
+	dir := t.TempDir()
+	foreign := []string{
+		"song.wav",                // the user's own file
+		"notes.json",              // the user's own file
+		"20250301-110000-000.wav", // session name without a sidecar
+	}
+	for _, name := range foreign {
+		if err := os.WriteFile(filepath.Join(dir, name), make([]byte, 64000), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	a := New(Config{Dir: dir, MaxCount: 1, MaxSize: 1})
+	if _, err := a.Save(testSession(time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC))); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	for _, name := range foreign {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("%s was removed by pruning: %v", name, err)
+		}
+	}
+}
@@ -204,13 +204,23 @@ func RemovePidFile() error {
 }
 
 func SendCommand(cmd byte) (string, error) {
+	return SendCommandArg(cmd, "")
+}
+
+// SendCommandArg sends a command with an argument, which follows the command
+// byte after a space ("t monitor")
+func SendCommandArg(cmd byte, arg string) (string, error) {
 	c, err := Dial()
 	if err != nil {
 		return "", fmt.Errorf("failed to connect to daemon: %w", err)
 	}
 	defer c.Close()
 
-	_, err = c.Write([]byte{cmd, '\n'})
+	line := []byte{cmd}
+	if arg != "" {
+		line = append(append(line, ' '), arg...)
+	}
+	_, err = c.Write(append(line, '\n'))
 	if err != nil {
 		return "", fmt.Errorf("failed to send command: %w", err)
 	}
@@ -222,3 +232,30 @@ func SendCommand(cmd byte) (string, error) {
 
 	return resp, nil
 }
+
+// StreamCommand sends cmd and passes every response line to onLine until the
+// daemon closes the connection or onLine returns false
+func StreamCommand(cmd byte, onLine func(line string) bool) error {
+	c, err := Dial()
+	if err != nil {
+		return fmt.Errorf("failed to connect to daemon: %w", err)
+	}
+	defer c.Close()
+
+	_, err = c.Write([]byte{cmd, '\n'})
+	if err != nil {
+		return fmt.Errorf("failed to send command: %w", err)
+	}
+
+	scanner := bufio.NewScanner(c)
+	for scanner.Scan() {
+		if !onLine(scanner.Text() + "\n") {
+			return nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+
+	return nil
+}
@@ -291,6 +291,67 @@ func TestSendCommand(t *testing.T) {
 	}
 }
 
+func TestStreamCommand(t *testing.T) {
+	tempDir := t.TempDir()
+	originalCacheDir := os.Getenv("XDG_CACHE_HOME")
+	os.Setenv("XDG_CACHE_HOME", tempDir)
+	defer func() {
+		if originalCacheDir == "" {
+			os.Unsetenv("XDG_CACHE_HOME")
+		} else {
+			os.Setenv("XDG_CACHE_HOME", originalCacheDir)
+		}
+	}()
+
+	sm, err := newSocketManager()
+	if err != nil {
+		t.Fatalf("Failed to create socket manager: %v", err)
+	}
+
+	listener, err := sm.listen()
+	if err != nil {
+		t.Fatalf("Failed to start listener: %v", err)
+	}
+	defer listener.Close()
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		buf := make([]byte, 2)
+		conn.Read(buf)
+		conn.Write([]byte("LEVEL rms=0.1000 peak=0.2000\nLEVEL rms=0.3000 peak=0.4000\nOK stopped status=idle\n"))
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+
+	var lines []string
+	err = StreamCommand('l', func(line string) bool {
+		lines = append(lines, line)
+		return true
+	})
+	if err != nil {
+		t.Fatalf("StreamCommand() error = %v", err)
+	}
+
+	expected := []string{
+		"LEVEL rms=0.1000 peak=0.2000\n",
+		"LEVEL rms=0.3000 peak=0.4000\n",
+		"OK stopped status=idle\n",
+	}
+	if len(lines) != len(expected) {
+		t.Fatalf("StreamCommand() received %d lines, want %d", len(lines), len(expected))
+	}
+	for i, line := range lines {
+		if line != expected[i] {
+			t.Errorf("line %d = %q, want %q", i, line, expected[i])
+		}
+	}
+}
+
 func TestCheckExistingDaemon(t *testing.T) {
 	// Test with no existing daemon
 	t.Run("no existing daemon", func(t *testing.T) {
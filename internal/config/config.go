@@ -1,35 +1,73 @@
 package config
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"net/url"
 	"os"
 	"path/filepath"
 	"reflect"
+	"strings"
 	"time"
 
 	"github.com/BurntSushi/toml"
+	"github.com/leonardotrapani/hyprvoice/internal/archive"
 	"github.com/leonardotrapani/hyprvoice/internal/injection"
 	"github.com/leonardotrapani/hyprvoice/internal/notify"
 	"github.com/leonardotrapani/hyprvoice/internal/recording"
 	"github.com/leonardotrapani/hyprvoice/internal/transcriber"
 )
 
+// listDevices enumerates capture devices for validation, replaceable in tests
+var listDevices = recording.ListDevices
+
 type Config struct {
 	Recording     RecordingConfig     `toml:"recording"`
 	Transcription TranscriptionConfig `toml:"transcription"`
 	Injection     InjectionConfig     `toml:"injection"`
 	Notifications NotificationsConfig `toml:"notifications"`
+	Archive       ArchiveConfig       `toml:"archive"`
 }
 
 type RecordingConfig struct {
+	Backends          []string      `toml:"backends"`
 	SampleRate        int           `toml:"sample_rate"`
 	Channels          int           `toml:"channels"`
 	Format            string        `toml:"format"`
 	BufferSize        int           `toml:"buffer_size"`
 	Device            string        `toml:"device"`
+	FallbackDevices   []string      `toml:"fallback_devices"` // Used in order when the device disappears mid-recording
+	Source            string        `toml:"source"`           // "mic", "monitor" (system audio) or "mixed"
+	MonitorDevice     string        `toml:"monitor_device"`   // Sink to capture for monitor/mixed; empty for the default
 	ChannelBufferSize int           `toml:"channel_buffer_size"`
 	Timeout           time.Duration `toml:"timeout"`
+	TimeoutWarning    time.Duration `toml:"timeout_warning"` // Notify this long before the timeout
+	TimeoutAction     string        `toml:"timeout_action"`  // "transcribe" or "abort"
+
+	// Voice activity detection: stop automatically after trailing silence
+	VADEnabled         bool          `toml:"vad_enabled"`
+	VADSilenceDuration time.Duration `toml:"vad_silence_duration"`
+	VADThreshold       float64       `toml:"vad_threshold"`
+	VADMinSpeech       time.Duration `toml:"vad_min_speech"`
+
+	// Warm capture: keep the microphone open between recordings
+	Warm    bool          `toml:"warm"`
+	PreRoll time.Duration `toml:"pre_roll"`
+
+	// Preprocessing applied to the audio before it is transcribed
+	DCRemoval          bool    `toml:"dc_removal"`
+	HighPassCutoff     float64 `toml:"highpass_cutoff"`
+	Normalize          bool    `toml:"normalize"`
+	NormalizeTarget    float64 `toml:"normalize_target"`
+	NormalizeMaxGain   float64 `toml:"normalize_max_gain"`
+	NoiseGate          bool    `toml:"noise_gate"`
+	NoiseGateThreshold float64 `toml:"noise_gate_threshold"`
+
+	// Silence removal before upload
+	TrimSilence   bool          `toml:"trim_silence"`
+	TrimThreshold float64       `toml:"trim_threshold"`
+	MaxPause      time.Duration `toml:"max_pause"`
 }
 
 type TranscriptionConfig struct {
@@ -38,6 +76,69 @@ type TranscriptionConfig struct {
 	Language  string `toml:"language"`
 	Model     string `toml:"model"`
 	ServerURL string `toml:"server_url"` // For local whisper.cpp server
+
+	BaseURL  string            `toml:"base_url"` // For openai-compatible: API root of the server
+	Headers  map[string]string `toml:"headers"`  // For openai-compatible: extra HTTP headers
+	Endpoint string            `toml:"endpoint"` // For openai-compatible: "transcription" or "translation"
+
+	ModelPath  string `toml:"model_path"`  // For whisper-cli and a managed whisper-cpp server: ggml model file
+	WhisperCLI string `toml:"whisper_cli"` // For whisper-cli: binary name or path
+	Threads    int    `toml:"threads"`     // For whisper-cli and a managed server: CPU threads (0 = whisper.cpp default)
+	Translate  bool   `toml:"translate"`   // For whisper-cli: translate to English
+
+	ManageServer      bool          `toml:"manage_server"`       // For whisper-cpp: the daemon runs whisper-server itself
+	WhisperServer     string        `toml:"whisper_server"`      // For a managed server: binary name or path
+	ServerPort        int           `toml:"server_port"`         // For a managed server: port on 127.0.0.1
+	ServerIdleTimeout time.Duration `toml:"server_idle_timeout"` // For a managed server: stop it when unused this long (0 = never)
+
+	UploadFormat string `toml:"upload_format"` // "wav", "flac" or "opus", empty for wav
+
+	MaxUploadMB      int `toml:"max_upload_mb"`     // Recordings larger than this are split into chunks
+	ChunkConcurrency int `toml:"chunk_concurrency"` // Chunks transcribed at once (1 = sequential with context)
+
+	Streaming      bool          `toml:"streaming"`       // Transcribe during the recording, segment by segment
+	StreamingPause time.Duration `toml:"streaming_pause"` // Silence that ends a segment
+
+	Providers []ProviderConfig `toml:"providers"` // Ordered fallback chain; replaces provider when set
+}
+
+// ProviderConfig is one entry of the transcription.providers fallback chain.
+// Settings not listed here are shared with the [transcription] section.
+type ProviderConfig struct {
+	Provider  string            `toml:"provider"`
+	APIKey    string            `toml:"api_key"`
+	Model     string            `toml:"model"`
+	ServerURL string            `toml:"server_url"`
+	BaseURL   string            `toml:"base_url"`
+	Headers   map[string]string `toml:"headers"`
+	Endpoint  string            `toml:"endpoint"`
+
+	UploadFormat string `toml:"upload_format"` // Replaces the shared upload_format when set
+}
+
+// chain returns the providers to try in order: the providers list when it is
+// set, otherwise the single configured provider
+func (t TranscriptionConfig) chain() []TranscriptionConfig {
+	if len(t.Providers) == 0 {
+		return []TranscriptionConfig{t}
+	}
+	chain := make([]TranscriptionConfig, len(t.Providers))
+	for i, p := range t.Providers {
+		entry := t
+		entry.Provider = p.Provider
+		entry.APIKey = p.APIKey
+		entry.Model = p.Model
+		entry.ServerURL = p.ServerURL
+		entry.BaseURL = p.BaseURL
+		entry.Headers = p.Headers
+		entry.Endpoint = p.Endpoint
+		if p.UploadFormat != "" {
+			entry.UploadFormat = p.UploadFormat
+		}
+		entry.Providers = nil
+		chain[i] = entry
+	}
+	return chain
 }
 
 type InjectionConfig struct {
@@ -46,6 +147,15 @@ type InjectionConfig struct {
 	WtypeTimeout     time.Duration `toml:"wtype_timeout"`
 	WtypeDelay       time.Duration `toml:"wtype_delay"`
 	ClipboardTimeout time.Duration `toml:"clipboard_timeout"`
+	Live             bool          `toml:"live"` // Type each segment as soon as it is transcribed (implies transcription.streaming)
+}
+
+type ArchiveConfig struct {
+	Enabled   bool          `toml:"enabled"`
+	Dir       string        `toml:"dir"` // empty = $XDG_DATA_HOME/hyprvoice/recordings
+	MaxCount  int           `toml:"max_count"`
+	MaxAge    time.Duration `toml:"max_age"`
+	MaxSizeMB int           `toml:"max_size_mb"`
 }
 
 type NotificationsConfig struct {
@@ -66,6 +176,11 @@ type MessagesConfig struct {
 	OperationCancelled MessageConfig `toml:"operation_cancelled"`
 	RecordingAborted   MessageConfig `toml:"recording_aborted"`
 	InjectionAborted   MessageConfig `toml:"injection_aborted"`
+	RecordingPaused    MessageConfig `toml:"recording_paused"`
+	RecordingResumed   MessageConfig `toml:"recording_resumed"`
+	TimeoutWarning     MessageConfig `toml:"timeout_warning"`
+	DeviceSwitched     MessageConfig `toml:"device_switched"`
+	ProviderFallback   MessageConfig `toml:"provider_fallback"`
 }
 
 // Resolve merges user config with defaults from MessageDefs
@@ -102,28 +217,90 @@ func (m *MessagesConfig) Resolve() map[notify.MessageType]notify.Message {
 
 func (c *Config) ToRecordingConfig() recording.Config {
 	return recording.Config{
+		Backends:          c.Recording.Backends,
 		SampleRate:        c.Recording.SampleRate,
 		Channels:          c.Recording.Channels,
 		Format:            c.Recording.Format,
 		BufferSize:        c.Recording.BufferSize,
 		Device:            c.Recording.Device,
+		FallbackDevices:   c.Recording.FallbackDevices,
+		Input:             c.Recording.Source,
+		MonitorDevice:     c.Recording.MonitorDevice,
 		ChannelBufferSize: c.Recording.ChannelBufferSize,
 		Timeout:           c.Recording.Timeout,
+		VAD: recording.VADConfig{
+			Enabled:         c.Recording.VADEnabled,
+			SilenceDuration: c.Recording.VADSilenceDuration,
+			Threshold:       c.Recording.VADThreshold,
+			MinSpeech:       c.Recording.VADMinSpeech,
+		},
+		Warm:    c.Recording.Warm,
+		PreRoll: c.Recording.PreRoll,
+		DSP: recording.DSPConfig{
+			DCRemoval:          c.Recording.DCRemoval,
+			HighPassCutoff:     c.Recording.HighPassCutoff,
+			Normalize:          c.Recording.Normalize,
+			NormalizeTarget:    c.Recording.NormalizeTarget,
+			NormalizeMaxGain:   c.Recording.NormalizeMaxGain,
+			NoiseGate:          c.Recording.NoiseGate,
+			NoiseGateThreshold: c.Recording.NoiseGateThreshold,
+		},
 	}
 }
 
 func (c *Config) ToTranscriberConfig() transcriber.Config {
+	chain := c.Transcription.chain()
+	config := c.transcriberConfig(chain[0])
+	for _, fallback := range chain[1:] {
+		config.Fallbacks = append(config.Fallbacks, c.transcriberConfig(fallback))
+	}
+	return config
+}
+
+// transcriberConfig converts the settings of one provider of the chain
+func (c *Config) transcriberConfig(t TranscriptionConfig) transcriber.Config {
 	config := transcriber.Config{
-		Provider:  c.Transcription.Provider,
-		APIKey:    c.Transcription.APIKey,
-		Language:  c.Transcription.Language,
-		Model:     c.Transcription.Model,
-		ServerURL: c.Transcription.ServerURL,
+		Provider:  t.Provider,
+		APIKey:    t.APIKey,
+		Language:  t.Language,
+		Model:     t.Model,
+		ServerURL: t.ServerURL,
+
+		BaseURL:  t.BaseURL,
+		Headers:  t.Headers,
+		Endpoint: t.Endpoint,
+
+		ModelPath:  expandHome(t.ModelPath),
+		WhisperCLI: t.WhisperCLI,
+		Threads:    t.Threads,
+		Translate:  t.Translate,
+
+		UploadFormat: t.UploadFormat,
+
+		MaxUploadSize:    t.MaxUploadMB << 20,
+		ChunkConcurrency: t.ChunkConcurrency,
+
+		Streaming:      t.Streaming || c.Injection.Live,
+		StreamingPause: t.StreamingPause,
+
+		Trim: transcriber.TrimConfig{
+			Enabled:   c.Recording.TrimSilence,
+			Threshold: c.Recording.TrimThreshold,
+			MaxPause:  c.Recording.MaxPause,
+		},
+	}
+
+	// A managed server always listens on the configured local port
+	if serverConfig, ok := c.ToWhisperServerConfig(); ok && t.Provider == "whisper-cpp" {
+		config.ServerURL = serverConfig.URL()
 	}
 
+	// Recordings are converted to the fixed 16 kHz mono layout before they reach the transcriber
+	config.SampleRate, config.Channels = transcriber.DefaultSampleRate, transcriber.DefaultChannels
+
 	// Check for API key in environment variables if not in config
 	if config.APIKey == "" {
-		switch c.Transcription.Provider {
+		switch t.Provider {
 		case "openai":
 			config.APIKey = os.Getenv("OPENAI_API_KEY")
 		case "groq-transcription", "groq-translation":
@@ -136,6 +313,60 @@ func (c *Config) ToTranscriberConfig() transcriber.Config {
 	return config
 }
 
+// ToWhisperServerConfig returns the whisper.cpp server the daemon should run,
+// and false when the server is not managed by hyprvoice
+func (c *Config) ToWhisperServerConfig() (transcriber.WhisperServerConfig, bool) {
+	if !c.Transcription.ManageServer {
+		return transcriber.WhisperServerConfig{}, false
+	}
+	managed := false
+	for _, t := range c.Transcription.chain() {
+		managed = managed || t.Provider == "whisper-cpp"
+	}
+	if !managed {
+		return transcriber.WhisperServerConfig{}, false
+	}
+	return transcriber.WhisperServerConfig{
+		Binary:      c.Transcription.WhisperServer,
+		ModelPath:   expandHome(c.Transcription.ModelPath),
+		Port:        c.Transcription.ServerPort,
+		Threads:     c.Transcription.Threads,
+		IdleTimeout: c.Transcription.ServerIdleTimeout,
+	}, true
+}
+
+// expandHome replaces a leading ~ with the home directory, leaving the path
+// as it is when there is none
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~"))
+}
+
+// ToArchiveConfig resolves the archive directory, expanding a leading ~
+func (c *Config) ToArchiveConfig() (archive.Config, error) {
+	dir := expandHome(c.Archive.Dir)
+	if dir == "" {
+		defaultDir, err := archive.DefaultDir()
+		if err != nil {
+			return archive.Config{}, err
+		}
+		dir = defaultDir
+	}
+
+	return archive.Config{
+		Dir:      dir,
+		MaxCount: c.Archive.MaxCount,
+		MaxAge:   c.Archive.MaxAge,
+		MaxSize:  int64(c.Archive.MaxSizeMB) * 1024 * 1024,
+	}, nil
+}
+
 func (c *Config) ToInjectionConfig() injection.Config {
 	return injection.Config{
 		Backends:         c.Injection.Backends,
@@ -163,19 +394,195 @@ func (c *Config) Validate() error {
 	if c.Recording.Format == "" {
 		return fmt.Errorf("invalid recording.format: empty")
 	}
+	validFormats := map[string]bool{"s16": true, "s24": true, "s32": true, "f32": true}
+	if !validFormats[c.Recording.Format] {
+		return fmt.Errorf("invalid recording.format: %q (must be s16, s24, s32, or f32)", c.Recording.Format)
+	}
 	if c.Recording.Timeout <= 0 {
 		return fmt.Errorf("invalid recording.timeout: %v", c.Recording.Timeout)
 	}
+	if c.Recording.TimeoutWarning < 0 || c.Recording.TimeoutWarning >= c.Recording.Timeout {
+		return fmt.Errorf("invalid recording.timeout_warning: %v (must be shorter than recording.timeout)", c.Recording.TimeoutWarning)
+	}
+	switch c.Recording.TimeoutAction {
+	case "", "transcribe", "abort":
+	default:
+		return fmt.Errorf("invalid recording.timeout_action: %s (must be transcribe or abort)", c.Recording.TimeoutAction)
+	}
+	if c.Recording.VADEnabled {
+		if c.Recording.VADSilenceDuration <= 0 {
+			return fmt.Errorf("invalid recording.vad_silence_duration: %v", c.Recording.VADSilenceDuration)
+		}
+		if c.Recording.VADThreshold <= 0 || c.Recording.VADThreshold >= 1 {
+			return fmt.Errorf("invalid recording.vad_threshold: %v (must be between 0 and 1)", c.Recording.VADThreshold)
+		}
+		if c.Recording.VADMinSpeech < 0 {
+			return fmt.Errorf("invalid recording.vad_min_speech: %v", c.Recording.VADMinSpeech)
+		}
+	}
+	if c.Recording.PreRoll < 0 || c.Recording.PreRoll > c.Recording.Timeout {
+		return fmt.Errorf("invalid recording.pre_roll: %v (must be between 0 and recording.timeout)", c.Recording.PreRoll)
+	}
+	// The DSP runs after conversion to the transcriber's 16 kHz, not at the capture rate
+	if c.Recording.HighPassCutoff < 0 || c.Recording.HighPassCutoff >= float64(transcriber.DefaultSampleRate)/2 {
+		return fmt.Errorf("invalid recording.highpass_cutoff: %v (must be between 0 and %d Hz)", c.Recording.HighPassCutoff, transcriber.DefaultSampleRate/2)
+	}
+	if c.Recording.Normalize {
+		if c.Recording.NormalizeTarget <= 0 || c.Recording.NormalizeTarget >= 1 {
+			return fmt.Errorf("invalid recording.normalize_target: %v (must be between 0 and 1)", c.Recording.NormalizeTarget)
+		}
+		if c.Recording.NormalizeMaxGain < 1 {
+			return fmt.Errorf("invalid recording.normalize_max_gain: %v (must be at least 1)", c.Recording.NormalizeMaxGain)
+		}
+	}
+	if c.Recording.NoiseGate && (c.Recording.NoiseGateThreshold <= 0 || c.Recording.NoiseGateThreshold >= 1) {
+		return fmt.Errorf("invalid recording.noise_gate_threshold: %v (must be between 0 and 1)", c.Recording.NoiseGateThreshold)
+	}
+	if c.Recording.TrimSilence {
+		if c.Recording.TrimThreshold <= 0 || c.Recording.TrimThreshold >= 1 {
+			return fmt.Errorf("invalid recording.trim_threshold: %v (must be between 0 and 1)", c.Recording.TrimThreshold)
+		}
+		if c.Recording.MaxPause <= 0 {
+			return fmt.Errorf("invalid recording.max_pause: %v", c.Recording.MaxPause)
+		}
+	}
+	validRecordingBackends := map[string]bool{"pw-record": true, "parecord": true, "arecord": true, "ffmpeg": true}
+	for _, backend := range c.Recording.Backends {
+		if !validRecordingBackends[backend] {
+			return fmt.Errorf("invalid recording.backends: unknown backend %q (must be pw-record, parecord, arecord, or ffmpeg)", backend)
+		}
+	}
+	switch c.Recording.Source {
+	case "", recording.InputMic, recording.InputMonitor, recording.InputMixed:
+	default:
+		return fmt.Errorf("invalid recording.source: %s (must be mic, monitor or mixed)", c.Recording.Source)
+	}
+	if c.Recording.Source != "" && c.Recording.Source != recording.InputMic &&
+		len(c.Recording.Backends) == 1 && c.Recording.Backends[0] == "arecord" {
+		return fmt.Errorf("invalid recording.source: %s (arecord cannot capture system audio)", c.Recording.Source)
+	}
+	// With fallback_devices an unplugged device is expected and only warned
+	// about; without them every recording would fail
+	if len(c.Recording.FallbackDevices) == 0 {
+		if err := c.CheckDevice(); err != nil {
+			return err
+		}
+	}
+
+	// Archive
+	if c.Archive.MaxCount < 0 {
+		return fmt.Errorf("invalid archive.max_count: %d", c.Archive.MaxCount)
+	}
+	if c.Archive.MaxAge < 0 {
+		return fmt.Errorf("invalid archive.max_age: %v", c.Archive.MaxAge)
+	}
+	if c.Archive.MaxSizeMB < 0 {
+		return fmt.Errorf("invalid archive.max_size_mb: %d", c.Archive.MaxSizeMB)
+	}
 
 	// Transcription
-	if c.Transcription.Provider == "" {
-		return fmt.Errorf("invalid transcription.provider: empty")
+	if err := c.ValidateTranscription(); err != nil {
+		return err
 	}
 
+	// Injection
+	if len(c.Injection.Backends) == 0 {
+		return fmt.Errorf("invalid injection.backends: empty (must have at least one backend)")
+	}
+	validBackends := map[string]bool{"ydotool": true, "wtype": true, "clipboard": true}
+	for _, backend := range c.Injection.Backends {
+		if !validBackends[backend] {
+			return fmt.Errorf("invalid injection.backends: unknown backend %q (must be ydotool, wtype, or clipboard)", backend)
+		}
+	}
+	if c.Injection.YdotoolTimeout <= 0 {
+		return fmt.Errorf("invalid injection.ydotool_timeout: %v", c.Injection.YdotoolTimeout)
+	}
+	if c.Injection.WtypeTimeout <= 0 {
+		return fmt.Errorf("invalid injection.wtype_timeout: %v", c.Injection.WtypeTimeout)
+	}
+	if c.Injection.ClipboardTimeout <= 0 {
+		return fmt.Errorf("invalid injection.clipboard_timeout: %v", c.Injection.ClipboardTimeout)
+	}
+
+	// Notifications
+	validTypes := map[string]bool{"desktop": true, "log": true, "none": true}
+	if !validTypes[c.Notifications.Type] {
+		return fmt.Errorf("invalid notifications.type: %s (must be desktop, log, or none)", c.Notifications.Type)
+	}
+
+	return nil
+}
+
+// ValidateTranscription checks only the [transcription] section, for
+// transcribing files without the recording setup
+func (c *Config) ValidateTranscription() error {
+	// A single provider, or the chain of providers tried in order
+	if len(c.Transcription.Providers) == 0 {
+		if c.Transcription.Provider == "" {
+			return fmt.Errorf("invalid transcription.provider: empty")
+		}
+		if err := validateProvider(c.Transcription); err != nil {
+			return err
+		}
+	} else {
+		for i, t := range c.Transcription.chain() {
+			if t.Provider == "" {
+				return fmt.Errorf("invalid transcription.providers[%d].provider: empty", i)
+			}
+			if !validUploadFormat(c.Transcription.Providers[i].UploadFormat) {
+				return fmt.Errorf("invalid transcription.providers[%d].upload_format: %s (must be wav, flac or opus)", i, c.Transcription.Providers[i].UploadFormat)
+			}
+			if err := validateProvider(t); err != nil {
+				return fmt.Errorf("transcription.providers[%d] (%s): %w", i, t.Provider, err)
+			}
+		}
+	}
+
+	if !validUploadFormat(c.Transcription.UploadFormat) {
+		return fmt.Errorf("invalid transcription.upload_format: %s (must be wav, flac or opus)", c.Transcription.UploadFormat)
+	}
+	if c.Transcription.MaxUploadMB < 0 {
+		return fmt.Errorf("invalid transcription.max_upload_mb: %d", c.Transcription.MaxUploadMB)
+	}
+	if c.Transcription.ChunkConcurrency < 0 {
+		return fmt.Errorf("invalid transcription.chunk_concurrency: %d", c.Transcription.ChunkConcurrency)
+	}
+	if c.Transcription.StreamingPause < 0 {
+		return fmt.Errorf("invalid transcription.streaming_pause: %v", c.Transcription.StreamingPause)
+	}
+	return nil
+}
+
+// CheckDevice reports a configured capture device that is not plugged in.
+// Validate runs it when no fallback_devices are set; with them an unplugged
+// device is a valid config, so callers only warn about it. Enumeration
+// failures are only logged so the check is skipped without PipeWire.
+func (c *Config) CheckDevice() error {
+	device := c.Recording.Device
+	// Device names are only known for PipeWire; other backends use their own naming
+	if device == "" || (len(c.Recording.Backends) > 0 && c.Recording.Backends[0] != "pw-record") {
+		return nil
+	}
+
+	devices, err := listDevices(context.Background())
+	if err != nil {
+		log.Printf("Config: skipping recording.device check: %v", err)
+		return nil
+	}
+
+	if _, ok := recording.FindDevice(devices, device); !ok {
+		return fmt.Errorf("recording.device %q not found (run 'hyprvoice devices' to list capture devices)", device)
+	}
+	return nil
+}
+
+// validateProvider checks the settings of one transcription provider
+func validateProvider(t TranscriptionConfig) error {
 	// Validate provider-specific settings
-	switch c.Transcription.Provider {
+	switch t.Provider {
 	case "openai":
-		apiKey := c.Transcription.APIKey
+		apiKey := t.APIKey
 		if apiKey == "" {
 			apiKey = os.Getenv("OPENAI_API_KEY")
 		}
@@ -184,12 +591,12 @@ func (c *Config) Validate() error {
 		}
 
 		// Validate language code if provided (empty string means auto-detect)
-		if c.Transcription.Language != "" && !isValidLanguageCode(c.Transcription.Language) {
-			return fmt.Errorf("invalid transcription.language: %s (use empty string for auto-detect or ISO-639-1 codes like 'en', 'es', 'fr')", c.Transcription.Language)
+		if t.Language != "" && !isValidLanguageCode(t.Language) {
+			return fmt.Errorf("invalid transcription.language: %s (use empty string for auto-detect or ISO-639-1 codes like 'en', 'es', 'fr')", t.Language)
 		}
 
 	case "groq-transcription":
-		apiKey := c.Transcription.APIKey
+		apiKey := t.APIKey
 		if apiKey == "" {
 			apiKey = os.Getenv("GROQ_API_KEY")
 		}
@@ -198,18 +605,18 @@ func (c *Config) Validate() error {
 		}
 
 		// Validate language code if provided (empty string means auto-detect)
-		if c.Transcription.Language != "" && !isValidLanguageCode(c.Transcription.Language) {
-			return fmt.Errorf("invalid transcription.language: %s (use empty string for auto-detect or ISO-639-1 codes like 'en', 'es', 'fr')", c.Transcription.Language)
+		if t.Language != "" && !isValidLanguageCode(t.Language) {
+			return fmt.Errorf("invalid transcription.language: %s (use empty string for auto-detect or ISO-639-1 codes like 'en', 'es', 'fr')", t.Language)
 		}
 
 		// Validate Groq model
 		validGroqModels := map[string]bool{"whisper-large-v3": true, "whisper-large-v3-turbo": true}
-		if c.Transcription.Model != "" && !validGroqModels[c.Transcription.Model] {
-			return fmt.Errorf("invalid model for groq-transcription: %s (must be whisper-large-v3 or whisper-large-v3-turbo)", c.Transcription.Model)
+		if t.Model != "" && !validGroqModels[t.Model] {
+			return fmt.Errorf("invalid model for groq-transcription: %s (must be whisper-large-v3 or whisper-large-v3-turbo)", t.Model)
 		}
 
 	case "groq-translation":
-		apiKey := c.Transcription.APIKey
+		apiKey := t.APIKey
 		if apiKey == "" {
 			apiKey = os.Getenv("GROQ_API_KEY")
 		}
@@ -218,17 +625,17 @@ func (c *Config) Validate() error {
 		}
 
 		// For translation, language field hints at source language (output is always English)
-		if c.Transcription.Language != "" && !isValidLanguageCode(c.Transcription.Language) {
-			return fmt.Errorf("invalid transcription.language: %s (use empty string for auto-detect or ISO-639-1 codes like 'en', 'es', 'fr')", c.Transcription.Language)
+		if t.Language != "" && !isValidLanguageCode(t.Language) {
+			return fmt.Errorf("invalid transcription.language: %s (use empty string for auto-detect or ISO-639-1 codes like 'en', 'es', 'fr')", t.Language)
 		}
 
 		// Validate Groq translation model - only whisper-large-v3 is supported (no turbo)
-		if c.Transcription.Model != "" && c.Transcription.Model != "whisper-large-v3" {
-			return fmt.Errorf("invalid model for groq-translation: %s (must be whisper-large-v3, turbo version not supported for translation)", c.Transcription.Model)
+		if t.Model != "" && t.Model != "whisper-large-v3" {
+			return fmt.Errorf("invalid model for groq-translation: %s (must be whisper-large-v3, turbo version not supported for translation)", t.Model)
 		}
 
 	case "mistral-transcription":
-		apiKey := c.Transcription.APIKey
+		apiKey := t.APIKey
 		if apiKey == "" {
 			apiKey = os.Getenv("MISTRAL_API_KEY")
 		}
@@ -237,62 +644,113 @@ func (c *Config) Validate() error {
 		}
 
 		// Validate language code if provided (empty string means auto-detect)
-		if c.Transcription.Language != "" && !isValidLanguageCode(c.Transcription.Language) {
-			return fmt.Errorf("invalid transcription.language: %s (use empty string for auto-detect or ISO-639-1 codes like 'en', 'es', 'fr')", c.Transcription.Language)
+		if t.Language != "" && !isValidLanguageCode(t.Language) {
+			return fmt.Errorf("invalid transcription.language: %s (use empty string for auto-detect or ISO-639-1 codes like 'en', 'es', 'fr')", t.Language)
 		}
 
 		// Validate Mistral model
 		validMistralModels := map[string]bool{"voxtral-mini-latest": true, "voxtral-mini-2507": true}
-		if c.Transcription.Model != "" && !validMistralModels[c.Transcription.Model] {
-			return fmt.Errorf("invalid model for mistral-transcription: %s (must be voxtral-mini-latest or voxtral-mini-2507)", c.Transcription.Model)
+		if t.Model != "" && !validMistralModels[t.Model] {
+			return fmt.Errorf("invalid model for mistral-transcription: %s (must be voxtral-mini-latest or voxtral-mini-2507)", t.Model)
 		}
 
 	case "whisper-cpp":
-		if c.Transcription.ServerURL == "" {
-			return fmt.Errorf("whisper.cpp server URL required: set transcription.server_url in config (e.g., http://192.168.10.37:8025/inference)")
+		if t.ManageServer {
+			if err := validateModelPath(t.ModelPath); err != nil {
+				return err
+			}
+			if t.ServerPort < 1 || t.ServerPort > 65535 {
+				return fmt.Errorf("invalid transcription.server_port: %d (must be 1-65535)", t.ServerPort)
+			}
+			if t.ServerIdleTimeout < 0 {
+				return fmt.Errorf("invalid transcription.server_idle_timeout: %v (must be 0 to keep the server running, or more)", t.ServerIdleTimeout)
+			}
+			if t.Threads < 0 {
+				return fmt.Errorf("invalid transcription.threads: %d (must be 0 for the default or more)", t.Threads)
+			}
+		} else if t.ServerURL == "" {
+			return fmt.Errorf("whisper.cpp server URL required: set transcription.server_url in config (e.g., http://192.168.10.37:8025/inference), or manage_server = true with model_path")
+		}
+
+		// Validate language code if provided (empty string means auto-detect)
+		if t.Language != "" && !isValidLanguageCode(t.Language) {
+			return fmt.Errorf("invalid transcription.language: %s (use empty string for auto-detect or ISO-639-1 codes like 'en', 'es', 'fr')", t.Language)
+		}
+
+	case "openai-compatible":
+		// Any server speaking the OpenAI audio API; models are whatever it serves and the API key is optional
+		if t.BaseURL == "" {
+			return fmt.Errorf("openai-compatible base URL required: set transcription.base_url in config (e.g., http://localhost:8000/v1)")
+		}
+		if u, err := url.Parse(t.BaseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid transcription.base_url: %s (must be an http or https URL)", t.BaseURL)
+		}
+
+		switch t.Endpoint {
+		case "", transcriber.EndpointTranscription, transcriber.EndpointTranslation:
+		default:
+			return fmt.Errorf("invalid transcription.endpoint: %s (must be transcription or translation)", t.Endpoint)
+		}
+
+		for name := range t.Headers {
+			if name == "" || strings.ContainsAny(name, " :\r\n") {
+				return fmt.Errorf("invalid transcription.headers: %q is not a header name", name)
+			}
 		}
 
 		// Validate language code if provided (empty string means auto-detect)
-		if c.Transcription.Language != "" && !isValidLanguageCode(c.Transcription.Language) {
-			return fmt.Errorf("invalid transcription.language: %s (use empty string for auto-detect or ISO-639-1 codes like 'en', 'es', 'fr')", c.Transcription.Language)
+		if t.Language != "" && !isValidLanguageCode(t.Language) {
+			return fmt.Errorf("invalid transcription.language: %s (use empty string for auto-detect or ISO-639-1 codes like 'en', 'es', 'fr')", t.Language)
+		}
+
+	case "whisper-cli":
+		if err := validateModelPath(t.ModelPath); err != nil {
+			return err
+		}
+		if t.Threads < 0 {
+			return fmt.Errorf("invalid transcription.threads: %d (must be 0 for the default or more)", t.Threads)
+		}
+
+		// Validate language code if provided (empty string means auto-detect)
+		if t.Language != "" && !isValidLanguageCode(t.Language) {
+			return fmt.Errorf("invalid transcription.language: %s (use empty string for auto-detect or ISO-639-1 codes like 'en', 'es', 'fr')", t.Language)
 		}
 
 	default:
-		return fmt.Errorf("unsupported transcription.provider: %s (must be openai, groq-transcription, groq-translation, mistral-transcription, or whisper-cpp)", c.Transcription.Provider)
+		return fmt.Errorf("unsupported transcription.provider: %s (must be openai, groq-transcription, groq-translation, mistral-transcription, whisper-cpp, whisper-cli, or openai-compatible)", t.Provider)
 	}
 
-	// Model validation - not required for whisper-cpp (uses server's loaded model)
-	if c.Transcription.Provider != "whisper-cpp" && c.Transcription.Model == "" {
+	// Model validation - not required for whisper.cpp (the server's loaded model or model_path is used)
+	if t.Provider != "whisper-cpp" && t.Provider != "whisper-cli" && t.Model == "" {
 		return fmt.Errorf("invalid transcription.model: empty")
 	}
 
-	// Injection
-	if len(c.Injection.Backends) == 0 {
-		return fmt.Errorf("invalid injection.backends: empty (must have at least one backend)")
-	}
-	validBackends := map[string]bool{"ydotool": true, "wtype": true, "clipboard": true}
-	for _, backend := range c.Injection.Backends {
-		if !validBackends[backend] {
-			return fmt.Errorf("invalid injection.backends: unknown backend %q (must be ydotool, wtype, or clipboard)", backend)
-		}
-	}
-	if c.Injection.YdotoolTimeout <= 0 {
-		return fmt.Errorf("invalid injection.ydotool_timeout: %v", c.Injection.YdotoolTimeout)
+	return nil
+}
+
+// validateModelPath checks that a local whisper.cpp model file exists
+func validateModelPath(path string) error {
+	if path == "" {
+		return fmt.Errorf("whisper.cpp model required: set transcription.model_path in config (e.g., ~/models/ggml-base.en.bin)")
 	}
-	if c.Injection.WtypeTimeout <= 0 {
-		return fmt.Errorf("invalid injection.wtype_timeout: %v", c.Injection.WtypeTimeout)
+	info, err := os.Stat(expandHome(path))
+	if err != nil {
+		return fmt.Errorf("invalid transcription.model_path: %w", err)
 	}
-	if c.Injection.ClipboardTimeout <= 0 {
-		return fmt.Errorf("invalid injection.clipboard_timeout: %v", c.Injection.ClipboardTimeout)
+	if info.IsDir() {
+		return fmt.Errorf("invalid transcription.model_path: %s is a directory, not a ggml model file", path)
 	}
+	return nil
+}
 
-	// Notifications
-	validTypes := map[string]bool{"desktop": true, "log": true, "none": true}
-	if !validTypes[c.Notifications.Type] {
-		return fmt.Errorf("invalid notifications.type: %s (must be desktop, log, or none)", c.Notifications.Type)
+// validUploadFormat reports whether format names an upload format, empty for
+// the provider default
+func validUploadFormat(format string) bool {
+	switch format {
+	case "", transcriber.FormatWAV, transcriber.FormatFLAC, transcriber.FormatOpus:
+		return true
 	}
-
-	return nil
+	return false
 }
 
 func isValidLanguageCode(code string) bool {
@@ -356,7 +814,8 @@ func Load() (*Config, error) {
 
 	log.Printf("Config: loading configuration from %s", configPath)
 	var config Config
-	if _, err := toml.DecodeFile(configPath, &config); err != nil {
+	meta, err := toml.DecodeFile(configPath, &config)
+	if err != nil {
 		return nil, fmt.Errorf("failed to parse config file %s: %w", configPath, err)
 	}
 
@@ -367,10 +826,78 @@ func Load() (*Config, error) {
 		config.migrateInjectionMode(legacy.Injection.Mode)
 	}
 
+	config.applyDefaults(meta)
+
 	log.Printf("Config: configuration loaded successfully")
 	return &config, nil
 }
 
+// applyDefaults fills in settings that were added after a config file was
+// generated. Settings where 0 is a valid value are only filled in when the
+// file does not set them, as told by meta.
+func (c *Config) applyDefaults(meta toml.MetaData) {
+	if len(c.Recording.Backends) == 0 {
+		c.Recording.Backends = recording.DefaultBackends
+	}
+	if c.Recording.VADSilenceDuration == 0 {
+		c.Recording.VADSilenceDuration = 1500 * time.Millisecond
+	}
+	if c.Recording.VADThreshold == 0 {
+		c.Recording.VADThreshold = 0.02
+	}
+	if !meta.IsDefined("recording", "vad_min_speech") {
+		c.Recording.VADMinSpeech = 500 * time.Millisecond
+	}
+	if !meta.IsDefined("recording", "pre_roll") {
+		c.Recording.PreRoll = 500 * time.Millisecond
+	}
+	if c.Recording.NormalizeTarget == 0 {
+		c.Recording.NormalizeTarget = 0.1
+	}
+	if c.Recording.NormalizeMaxGain == 0 {
+		c.Recording.NormalizeMaxGain = 10
+	}
+	if c.Recording.NoiseGateThreshold == 0 {
+		c.Recording.NoiseGateThreshold = 0.01
+	}
+	if c.Recording.TrimThreshold == 0 {
+		c.Recording.TrimThreshold = 0.01
+	}
+	if c.Recording.MaxPause == 0 {
+		c.Recording.MaxPause = time.Second
+	}
+	if !meta.IsDefined("recording", "timeout_warning") {
+		c.Recording.TimeoutWarning = min(30*time.Second, c.Recording.Timeout/2)
+	}
+	if c.Recording.TimeoutAction == "" {
+		c.Recording.TimeoutAction = "transcribe"
+	}
+	if c.Recording.Source == "" {
+		c.Recording.Source = recording.InputMic
+	}
+	if c.Transcription.MaxUploadMB == 0 {
+		c.Transcription.MaxUploadMB = 24
+	}
+	if c.Transcription.ChunkConcurrency == 0 {
+		c.Transcription.ChunkConcurrency = 1
+	}
+	if c.Transcription.StreamingPause == 0 {
+		c.Transcription.StreamingPause = transcriber.DefaultStreamingPause
+	}
+	if c.Transcription.Endpoint == "" {
+		c.Transcription.Endpoint = transcriber.EndpointTranscription
+	}
+	if c.Transcription.WhisperCLI == "" {
+		c.Transcription.WhisperCLI = transcriber.DefaultWhisperCLI
+	}
+	if c.Transcription.WhisperServer == "" {
+		c.Transcription.WhisperServer = transcriber.DefaultWhisperServer
+	}
+	if c.Transcription.ServerPort == 0 {
+		c.Transcription.ServerPort = 8025
+	}
+}
+
 // migrateInjectionMode converts old mode field to new backends array
 func (c *Config) migrateInjectionMode(mode string) {
 	switch mode {
@@ -417,21 +944,72 @@ func SaveDefaultConfig() error {
 
 # Audio Recording Configuration
 [recording]
+  backends = ["pw-record"]     # Ordered capture fallback chain: "pw-record", "parecord", "arecord", "ffmpeg"
   sample_rate = 16000          # Audio sample rate in Hz (16000 recommended for speech)
   channels = 1                 # Number of audio channels (1 = mono, 2 = stereo)
-  format = "s16"               # Audio format (s16 = 16-bit signed integers)
+  format = "s16"               # Audio format: "s16", "s24", "s32" or "f32"
   buffer_size = 8192           # Internal buffer size in bytes (larger = less CPU, more latency)
-  device = ""                  # PipeWire audio device (empty = use default microphone)
+  device = ""                  # Capture device (empty = default microphone, see 'hyprvoice devices')
+  fallback_devices = []        # Devices to switch to, in order, if the device is unplugged mid-recording (then the default)
+  source = "mic"               # What to record: "mic", "monitor" (system audio) or "mixed" (both)
+  monitor_device = ""          # Sink whose output monitor/mixed records (empty = default output)
   channel_buffer_size = 30     # Audio frame buffer size (frames to buffer)
   timeout = "5m"               # Maximum recording duration (e.g., "30s", "2m", "5m")
+  timeout_warning = "30s"      # Notify this long before the timeout is reached (0 = no warning)
+  timeout_action = "transcribe" # At the timeout: "transcribe" what was recorded, or "abort" and discard it
+  vad_enabled = false          # Stop recording automatically after trailing silence (one keypress dictation)
+  vad_silence_duration = "1.5s" # Silence required after speech before auto-stop
+  vad_threshold = 0.02         # Energy threshold (0.0-1.0) separating speech from silence
+  vad_min_speech = "500ms"     # Minimum speech before silence can end the recording
+  warm = false                 # Keep the microphone open between recordings so the first syllable is never lost
+  pre_roll = "500ms"           # Audio from before the toggle included in each recording (warm mode only, 0 = none)
+  dc_removal = true            # Remove DC offset from cheap microphones
+  highpass_cutoff = 80.0       # High-pass filter in Hz against fan hum and rumble (0 = disabled)
+  normalize = true             # Automatic gain normalization for quiet microphones
+  normalize_target = 0.1       # Target speech level (0.0-1.0 RMS)
+  normalize_max_gain = 10.0    # Maximum gain applied to quiet input (10 = +20 dB)
+  noise_gate = false           # Mute audio below noise_gate_threshold between words
+  noise_gate_threshold = 0.01  # Level (0.0-1.0 RMS) below which the noise gate closes
+  trim_silence = true          # Cut leading/trailing silence and shorten long pauses before upload
+  trim_threshold = 0.01        # Level (0.0-1.0 RMS) below which audio counts as silence
+  max_pause = "1s"             # Longest pause kept between words
 
 # Speech Transcription Configuration
 [transcription]
-  provider = "openai"          # Transcription service: "openai", "groq-transcription", "groq-translation", "mistral-transcription", or "whisper-cpp"
+  provider = "openai"          # Transcription service: "openai", "groq-transcription", "groq-translation", "mistral-transcription", "whisper-cpp", "whisper-cli", or "openai-compatible"
   api_key = ""                 # API key (or set OPENAI_API_KEY/GROQ_API_KEY/MISTRAL_API_KEY environment variable)
   language = ""                # Language code (empty for auto-detect, "en", "it", "es", "fr", etc.)
-  model = "whisper-1"          # Model: OpenAI="whisper-1", Groq="whisper-large-v3", Mistral="voxtral-mini-latest" (not needed for whisper-cpp)
+  model = "whisper-1"          # Model: OpenAI="whisper-1", Groq="whisper-large-v3", Mistral="voxtral-mini-latest" (not needed for whisper-cpp/whisper-cli)
   server_url = ""              # For whisper-cpp only: local server URL (e.g., "http://192.168.10.37:8025/inference")
+  base_url = ""                # For openai-compatible only: API root (e.g., "http://localhost:8000/v1")
+  headers = {}                 # For openai-compatible only: extra HTTP headers (e.g., { "X-Api-Key" = "..." })
+  endpoint = "transcription"   # For openai-compatible only: "transcription" or "translation" (to English)
+  model_path = ""              # For whisper-cli or a managed server: ggml model file (e.g., "~/models/ggml-base.en.bin")
+  whisper_cli = "whisper-cli"  # For whisper-cli only: whisper.cpp command line binary
+  threads = 0                  # For whisper-cli or a managed server: CPU threads (0 = whisper.cpp default)
+  translate = false            # For whisper-cli only: translate speech to English text
+  manage_server = false        # For whisper-cpp only: run whisper-server with model_path on server_port instead of using server_url
+  whisper_server = "whisper-server" # For a managed server: whisper.cpp server binary
+  server_port = 8025           # For a managed server: port on 127.0.0.1
+  server_idle_timeout = "10m"  # For a managed server: stop it after this long unused (0 = keep running)
+  upload_format = ""           # Audio sent to the provider: "wav", "flac" or "opus" (needs ffmpeg); empty uses wav (whisper.cpp always gets wav)
+  max_upload_mb = 24           # Longer recordings are split at pauses into requests below this size
+  chunk_concurrency = 1        # Chunks transcribed at once (1 = in order, each using the previous text as context)
+  streaming = false            # Transcribe while you talk, segment by segment, so only the last one is left at stop
+  streaming_pause = "800ms"    # Silence that ends a segment in streaming mode
+
+  # Optional ordered fallback chain. When set it replaces provider above: each
+  # entry is tried in turn until one returns text, and the other settings in
+  # this section are shared. Entries take provider, api_key, model, server_url,
+  # base_url, headers, endpoint and upload_format.
+  # [[transcription.providers]]
+  #   provider = "groq-transcription"
+  #   model = "whisper-large-v3-turbo"
+  # [[transcription.providers]]
+  #   provider = "openai"
+  #   model = "whisper-1"
+  # [[transcription.providers]]
+  #   provider = "whisper-cli"
 
 # Text Injection Configuration
 [injection]
@@ -439,6 +1017,15 @@ func SaveDefaultConfig() error {
   ydotool_timeout = "5s"       # Timeout for ydotool commands
   wtype_timeout = "5s"         # Timeout for wtype commands
   clipboard_timeout = "3s"     # Timeout for clipboard operations
+  live = false                 # Type text segment by segment while you talk (uses streaming, needs ydotool or wtype)
+
+# Session Archive Configuration (keeps audio and transcripts for debugging)
+[archive]
+  enabled = false              # Save each session as WAV plus a JSON sidecar
+  dir = ""                     # Archive directory (empty = ~/.local/share/hyprvoice/recordings)
+  max_count = 100              # Keep at most this many sessions (0 = unlimited)
+  max_age = "720h"             # Delete sessions older than this (0 = keep forever)
+  max_size_mb = 500            # Total archive size limit in MB (0 = unlimited)
 
 # Desktop Notification Configuration
 [notifications]
@@ -464,6 +1051,21 @@ func SaveDefaultConfig() error {
   #     body = "Recording Aborted"
   #   [notifications.messages.injection_aborted]
   #     body = "Injection Aborted"
+  #   [notifications.messages.recording_paused]
+  #     title = "Hyprvoice"
+  #     body = "Recording Paused"
+  #   [notifications.messages.recording_resumed]
+  #     title = "Hyprvoice"
+  #     body = "Recording Resumed"
+  #   [notifications.messages.timeout_warning]
+  #     title = "Hyprvoice"
+  #     body = "Recording Time Almost Up"
+  #   [notifications.messages.device_switched]
+  #     title = "Hyprvoice"
+  #     body = "Microphone Disconnected... Switched Input"
+  #   [notifications.messages.provider_fallback]
+  #     title = "Hyprvoice"
+  #     body = "Transcribed with Fallback Provider"
   #
   # Emoji-only example (for minimal pill-style notifications):
   #   [notifications.messages.recording_started]
@@ -476,6 +1078,12 @@ func SaveDefaultConfig() error {
   #     title = ""
   #     body = "🔧"
 
+# Capture backend explanations:
+# - "pw-record": PipeWire (default). device is a PipeWire node name from 'hyprvoice devices'.
+# - "parecord": PulseAudio (or pipewire-pulse). device is a PulseAudio source name (pactl list short sources).
+# - "arecord": ALSA only, works without a sound server. device is an ALSA name like "hw:0" or "default".
+# - "ffmpeg": Captures via PulseAudio, or ALSA when device starts with "hw:"/"plughw:".
+#
 # Backend explanations:
 # - "ydotool": Uses ydotool (requires ydotoold daemon running). Most compatible with Chromium/Electron apps.
 # - "wtype": Uses wtype for Wayland. May have issues with some Chromium-based apps.
@@ -497,6 +1105,11 @@ func SaveDefaultConfig() error {
 #     Models: voxtral-mini-latest or voxtral-mini-2507
 # - "whisper-cpp": Local whisper.cpp server (requires server_url, no API key needed)
 #     Set server_url to your local server endpoint (e.g., "http://192.168.10.37:8025/inference")
+#     Or set manage_server = true and model_path to have the daemon start and stop whisper-server itself
+# - "whisper-cli": Runs whisper.cpp's whisper-cli directly, fully offline (requires model_path, no server or API key)
+#     Download ggml models from https://huggingface.co/ggerganov/whisper.cpp
+# - "openai-compatible": Any server with the OpenAI audio API (LocalAI, faster-whisper-server, vLLM, proxies)
+#     Requires base_url; model is whatever the server serves; api_key and headers are optional
 #
 # Language codes: Use empty string ("") for automatic detection, or specific codes like:
 # "en" (English), "it" (Italian), "es" (Spanish), "fr" (French), "de" (German), etc.
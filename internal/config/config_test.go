@@ -1,12 +1,16 @@
 package config
 
 import (
+	"context"
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/BurntSushi/toml"
 	"github.com/leonardotrapani/hyprvoice/internal/notify"
+	"github.com/leonardotrapani/hyprvoice/internal/recording"
 )
 
 // createTestConfig returns a valid configuration for testing
@@ -928,6 +932,617 @@ func TestConfig_Validate_RecordingTimeout(t *testing.T) {
 	}
 }
 
+func TestConfig_ApplyDefaults_ExplicitZero(t *testing.T) {
+	tests := []struct {
+		name  string
+		file  string
+		value func(*Config) time.Duration
+		want  time.Duration
+	}{
+		{"vad_min_speech unset", "[recording]\n", func(c *Config) time.Duration { return c.Recording.VADMinSpeech }, 500 * time.Millisecond},
+		{"vad_min_speech zero", "[recording]\nvad_min_speech = \"0s\"\n", func(c *Config) time.Duration { return c.Recording.VADMinSpeech }, 0},
+		{"pre_roll unset", "[recording]\n", func(c *Config) time.Duration { return c.Recording.PreRoll }, 500 * time.Millisecond},
+		{"pre_roll zero", "[recording]\npre_roll = \"0s\"\n", func(c *Config) time.Duration { return c.Recording.PreRoll }, 0},
+		{"timeout_warning unset", "[recording]\ntimeout = \"5m\"\n", func(c *Config) time.Duration { return c.Recording.TimeoutWarning }, 30 * time.Second},
+		{"timeout_warning zero", "[recording]\ntimeout = \"5m\"\ntimeout_warning = \"0s\"\n", func(c *Config) time.Duration { return c.Recording.TimeoutWarning }, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var config Config
+			meta, err := toml.Decode(tt.file, &config)
+			if err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			config.applyDefaults(meta)
+			if got := tt.value(&config); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfig_Validate_TimeoutBehavior(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(*Config)
+		wantErr bool
+	}{
+		{"defaults", func(c *Config) { c.applyDefaults(toml.MetaData{}) }, false},
+		{"abort", func(c *Config) { c.Recording.TimeoutAction = "abort" }, false},
+		{"unknown action", func(c *Config) { c.Recording.TimeoutAction = "ignore" }, true},
+		{"warning as long as the timeout", func(c *Config) { c.Recording.TimeoutWarning = c.Recording.Timeout }, true},
+		{"negative warning", func(c *Config) { c.Recording.TimeoutWarning = -time.Second }, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := createTestConfig()
+			tt.modify(config)
+			err := config.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConfig_Validate_Source(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(*Config)
+		wantErr bool
+	}{
+		{"defaults", func(c *Config) { c.applyDefaults(toml.MetaData{}) }, false},
+		{"monitor", func(c *Config) { c.Recording.Source = "monitor" }, false},
+		{"mixed with sink", func(c *Config) {
+			c.Recording.Source = "mixed"
+			c.Recording.MonitorDevice = "alsa_output.pci-0000_00_1f.3.analog-stereo"
+		}, false},
+		{"unknown source", func(c *Config) { c.Recording.Source = "speakers" }, true},
+		{"monitor with arecord only", func(c *Config) {
+			c.Recording.Source = "monitor"
+			c.Recording.Backends = []string{"arecord"}
+		}, true},
+		{"monitor with arecord fallback", func(c *Config) {
+			c.Recording.Source = "monitor"
+			c.Recording.Backends = []string{"parecord", "arecord"}
+		}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := createTestConfig()
+			tt.modify(config)
+			err := config.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConfig_Validate_OpenAICompatible(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(*Config)
+		wantErr bool
+	}{
+		{"any model without api key", func(c *Config) {}, false},
+		{"translation with headers", func(c *Config) {
+			c.Transcription.Endpoint = "translation"
+			c.Transcription.Headers = map[string]string{"X-Api-Key": "secret"}
+		}, false},
+		{"missing base url", func(c *Config) { c.Transcription.BaseURL = "" }, true},
+		{"base url without scheme", func(c *Config) { c.Transcription.BaseURL = "localhost:8000/v1" }, true},
+		{"missing model", func(c *Config) { c.Transcription.Model = "" }, true},
+		{"unknown endpoint", func(c *Config) { c.Transcription.Endpoint = "chat" }, true},
+		{"invalid header name", func(c *Config) { c.Transcription.Headers = map[string]string{"X Api": "secret"} }, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := createTestConfig()
+			config.Transcription = TranscriptionConfig{
+				Provider: "openai-compatible",
+				BaseURL:  "http://localhost:8000/v1",
+				Model:    "Systran/faster-distil-whisper-large-v3",
+			}
+			tt.modify(config)
+			err := config.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConfig_Validate_WhisperCLI(t *testing.T) {
+	modelPath := filepath.Join(t.TempDir(), "ggml-base.en.bin")
+	if err := os.WriteFile(modelPath, []byte("ggml"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(*Config)
+		wantErr bool
+	}{
+		{"model path without model name", func(c *Config) {}, false},
+		{"threads and translate", func(c *Config) {
+			c.Transcription.Threads = 8
+			c.Transcription.Translate = true
+		}, false},
+		{"missing model path", func(c *Config) { c.Transcription.ModelPath = "" }, true},
+		{"model file does not exist", func(c *Config) { c.Transcription.ModelPath = modelPath + ".missing" }, true},
+		{"model path is a directory", func(c *Config) { c.Transcription.ModelPath = filepath.Dir(modelPath) }, true},
+		{"negative threads", func(c *Config) { c.Transcription.Threads = -1 }, true},
+		{"invalid language", func(c *Config) { c.Transcription.Language = "english" }, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := createTestConfig()
+			config.Transcription = TranscriptionConfig{
+				Provider:  "whisper-cli",
+				ModelPath: modelPath,
+			}
+			tt.modify(config)
+			err := config.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConfig_Validate_ManagedWhisperServer(t *testing.T) {
+	modelPath := filepath.Join(t.TempDir(), "ggml-base.bin")
+	if err := os.WriteFile(modelPath, []byte("ggml"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(*Config)
+		wantErr bool
+	}{
+		{"managed without server url", func(c *Config) {}, false},
+		{"never stopped when idle", func(c *Config) { c.Transcription.ServerIdleTimeout = 0 }, false},
+		{"unmanaged without server url", func(c *Config) { c.Transcription.ManageServer = false }, true},
+		{"missing model path", func(c *Config) { c.Transcription.ModelPath = "" }, true},
+		{"port out of range", func(c *Config) { c.Transcription.ServerPort = 70000 }, true},
+		{"negative idle timeout", func(c *Config) { c.Transcription.ServerIdleTimeout = -time.Second }, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := createTestConfig()
+			config.Transcription = TranscriptionConfig{
+				Provider:          "whisper-cpp",
+				ManageServer:      true,
+				ModelPath:         modelPath,
+				ServerPort:        8025,
+				ServerIdleTimeout: 10 * time.Minute,
+			}
+			tt.modify(config)
+			err := config.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	config := createTestConfig()
+	config.Transcription = TranscriptionConfig{Provider: "whisper-cpp", ManageServer: true, ModelPath: modelPath, ServerPort: 9000, ServerURL: "http://elsewhere:8080/inference"}
+	serverConfig, ok := config.ToWhisperServerConfig()
+	if !ok || serverConfig.Port != 9000 || serverConfig.ModelPath != modelPath {
+		t.Errorf("ToWhisperServerConfig() = %+v, %v", serverConfig, ok)
+	}
+	if url := config.ToTranscriberConfig().ServerURL; url != "http://127.0.0.1:9000/inference" {
+		t.Errorf("ToTranscriberConfig().ServerURL = %q, want the managed server", url)
+	}
+
+	config.Transcription.ManageServer = false
+	if _, ok := config.ToWhisperServerConfig(); ok {
+		t.Errorf("ToWhisperServerConfig() reported a managed server with manage_server = false")
+	}
+}
+
+func TestConfig_ValidateTranscription(t *testing.T) {
+	config := createTestConfig()
+	config.Recording.SampleRate = 0
+	config.Injection.Backends = nil
+	if err := config.ValidateTranscription(); err != nil {
+		t.Errorf("ValidateTranscription() error = %v, want the other sections ignored", err)
+	}
+	if err := config.Validate(); err == nil {
+		t.Errorf("Validate() accepted an invalid recording section")
+	}
+
+	config.Transcription.Provider = "unknown"
+	if err := config.ValidateTranscription(); err == nil {
+		t.Errorf("ValidateTranscription() accepted an unknown provider")
+	}
+}
+
+func TestConfig_Validate_Providers(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(*Config)
+		wantErr bool
+	}{
+		{"valid chain", func(c *Config) {}, false},
+		{"chain replaces provider", func(c *Config) { c.Transcription.Provider = "" }, false},
+		{"unknown provider", func(c *Config) { c.Transcription.Providers[1].Provider = "unknown" }, true},
+		{"missing provider", func(c *Config) { c.Transcription.Providers[0].Provider = "" }, true},
+		{"missing server url", func(c *Config) { c.Transcription.Providers[1].ServerURL = "" }, true},
+		{"missing api key", func(c *Config) { c.Transcription.Providers[0].APIKey = "" }, true},
+		{"upload format", func(c *Config) { c.Transcription.Providers[1].UploadFormat = "wav" }, false},
+		{"invalid upload format", func(c *Config) { c.Transcription.Providers[0].UploadFormat = "mp3" }, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("GROQ_API_KEY", "")
+			config := createTestConfig()
+			config.Transcription.Providers = []ProviderConfig{
+				{Provider: "groq-transcription", APIKey: "gsk-test", Model: "whisper-large-v3"},
+				{Provider: "whisper-cpp", ServerURL: "http://127.0.0.1:8080/inference"},
+			}
+			tt.modify(config)
+			err := config.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConfig_ToTranscriberConfig_Providers(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "env-key")
+	config := createTestConfig()
+	config.Transcription.Language = "de"
+	config.Transcription.UploadFormat = "opus"
+	config.Transcription.Providers = []ProviderConfig{
+		{Provider: "whisper-cpp", ServerURL: "http://127.0.0.1:8080/inference", UploadFormat: "wav"},
+		{Provider: "openai", Model: "whisper-1"},
+	}
+
+	transCfg := config.ToTranscriberConfig()
+	if transCfg.Provider != "whisper-cpp" || transCfg.ServerURL != "http://127.0.0.1:8080/inference" {
+		t.Errorf("primary = %s %s, want the first entry", transCfg.Provider, transCfg.ServerURL)
+	}
+	if transCfg.UploadFormat != "wav" {
+		t.Errorf("primary UploadFormat = %q, want the entry's own", transCfg.UploadFormat)
+	}
+	if len(transCfg.Fallbacks) != 1 {
+		t.Fatalf("got %d fallbacks, want 1", len(transCfg.Fallbacks))
+	}
+	fallback := transCfg.Fallbacks[0]
+	if fallback.Provider != "openai" || fallback.Model != "whisper-1" {
+		t.Errorf("fallback = %s %s, want openai whisper-1", fallback.Provider, fallback.Model)
+	}
+	if fallback.APIKey != "env-key" {
+		t.Errorf("fallback APIKey = %q, want it from OPENAI_API_KEY", fallback.APIKey)
+	}
+	if fallback.Language != "de" {
+		t.Errorf("fallback Language = %q, want the shared setting", fallback.Language)
+	}
+	if fallback.UploadFormat != "opus" {
+		t.Errorf("fallback UploadFormat = %q, want the shared setting", fallback.UploadFormat)
+	}
+}
+
+func TestConfig_Validate_VAD(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(*Config)
+		wantErr bool
+	}{
+		{"disabled ignores zero values", func(c *Config) {}, false},
+		{"enabled with valid values", func(c *Config) {
+			c.Recording.VADEnabled = true
+			c.Recording.VADSilenceDuration = time.Second
+			c.Recording.VADThreshold = 0.02
+			c.Recording.VADMinSpeech = 500 * time.Millisecond
+		}, false},
+		{"enabled without silence duration", func(c *Config) {
+			c.Recording.VADEnabled = true
+			c.Recording.VADThreshold = 0.02
+		}, true},
+		{"enabled with threshold out of range", func(c *Config) {
+			c.Recording.VADEnabled = true
+			c.Recording.VADSilenceDuration = time.Second
+			c.Recording.VADThreshold = 1.5
+		}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := createTestConfig()
+			tt.modify(config)
+			err := config.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConfig_Validate_DSP(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(*Config)
+		wantErr bool
+	}{
+		{"disabled ignores zero values", func(c *Config) {}, false},
+		{"all stages enabled", func(c *Config) {
+			c.Recording.DCRemoval = true
+			c.Recording.HighPassCutoff = 80
+			c.Recording.Normalize = true
+			c.Recording.NormalizeTarget = 0.1
+			c.Recording.NormalizeMaxGain = 10
+			c.Recording.NoiseGate = true
+			c.Recording.NoiseGateThreshold = 0.01
+		}, false},
+		{"negative highpass cutoff", func(c *Config) {
+			c.Recording.HighPassCutoff = -1
+		}, true},
+		{"highpass cutoff above nyquist", func(c *Config) {
+			c.Recording.HighPassCutoff = float64(c.Recording.SampleRate)
+		}, true},
+		{"highpass cutoff above nyquist of the converted stream", func(c *Config) {
+			c.Recording.SampleRate = 48000
+			c.Recording.HighPassCutoff = 8000
+		}, true},
+		{"normalize target out of range", func(c *Config) {
+			c.Recording.Normalize = true
+			c.Recording.NormalizeTarget = 1.5
+			c.Recording.NormalizeMaxGain = 10
+		}, true},
+		{"normalize max gain below one", func(c *Config) {
+			c.Recording.Normalize = true
+			c.Recording.NormalizeTarget = 0.1
+			c.Recording.NormalizeMaxGain = 0.5
+		}, true},
+		{"noise gate without threshold", func(c *Config) {
+			c.Recording.NoiseGate = true
+		}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := createTestConfig()
+			tt.modify(config)
+			err := config.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConfig_Validate_TrimSilence(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(*Config)
+		wantErr bool
+	}{
+		{"disabled ignores zero values", func(c *Config) {}, false},
+		{"enabled with valid values", func(c *Config) {
+			c.Recording.TrimSilence = true
+			c.Recording.TrimThreshold = 0.01
+			c.Recording.MaxPause = time.Second
+		}, false},
+		{"enabled with threshold out of range", func(c *Config) {
+			c.Recording.TrimSilence = true
+			c.Recording.TrimThreshold = 2
+			c.Recording.MaxPause = time.Second
+		}, true},
+		{"enabled without max pause", func(c *Config) {
+			c.Recording.TrimSilence = true
+			c.Recording.TrimThreshold = 0.01
+		}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := createTestConfig()
+			tt.modify(config)
+			err := config.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConfig_Validate_UploadFormat(t *testing.T) {
+	tests := []struct {
+		format  string
+		wantErr bool
+	}{
+		{"", false},
+		{"wav", false},
+		{"flac", false},
+		{"opus", false},
+		{"mp3", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			config := createTestConfig()
+			config.Transcription.UploadFormat = tt.format
+			err := config.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConfig_Chunking(t *testing.T) {
+	config := createTestConfig()
+	config.applyDefaults(toml.MetaData{})
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+
+	transcriberConfig := config.ToTranscriberConfig()
+	if transcriberConfig.MaxUploadSize != 24<<20 || transcriberConfig.ChunkConcurrency != 1 {
+		t.Errorf("ToTranscriberConfig() = %d bytes, concurrency %d, want 24 MB sequential",
+			transcriberConfig.MaxUploadSize, transcriberConfig.ChunkConcurrency)
+	}
+
+	config.Transcription.ChunkConcurrency = -1
+	if err := config.Validate(); err == nil {
+		t.Errorf("Validate() accepted a negative chunk_concurrency")
+	}
+}
+
+func TestConfig_ToArchiveConfig(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip("no home directory")
+	}
+	t.Setenv("XDG_DATA_HOME", "/data")
+
+	tests := []struct {
+		name    string
+		dir     string
+		wantDir string
+	}{
+		{"default", "", "/data/hyprvoice/recordings"},
+		{"home relative", "~/voice", filepath.Join(home, "voice")},
+		{"absolute", "/srv/voice", "/srv/voice"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := createTestConfig()
+			config.Archive = ArchiveConfig{Enabled: true, Dir: tt.dir, MaxCount: 10, MaxSizeMB: 2}
+
+			got, err := config.ToArchiveConfig()
+			if err != nil {
+				t.Fatalf("ToArchiveConfig() error = %v", err)
+			}
+			if got.Dir != tt.wantDir {
+				t.Errorf("Dir = %q, want %q", got.Dir, tt.wantDir)
+			}
+			if got.MaxCount != 10 || got.MaxSize != 2*1024*1024 {
+				t.Errorf("limits = %d sessions, %d bytes", got.MaxCount, got.MaxSize)
+			}
+		})
+	}
+}
+
+func TestConfig_Validate_Archive(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(*Config)
+		wantErr bool
+	}{
+		{"unlimited", func(c *Config) { c.Archive.Enabled = true }, false},
+		{"negative count", func(c *Config) { c.Archive.MaxCount = -1 }, true},
+		{"negative age", func(c *Config) { c.Archive.MaxAge = -time.Hour }, true},
+		{"negative size", func(c *Config) { c.Archive.MaxSizeMB = -1 }, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := createTestConfig()
+			tt.modify(config)
+			err := config.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConfig_Validate_RecordingBackends(t *testing.T) {
+	tests := []struct {
+		name     string
+		backends []string
+		wantErr  bool
+	}{
+		{"empty uses default", nil, false},
+		{"single backend", []string{"arecord"}, false},
+		{"fallback chain", []string{"pw-record", "parecord", "arecord", "ffmpeg"}, false},
+		{"unknown backend", []string{"pw-record", "oss"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := createTestConfig()
+			config.Recording.Backends = tt.backends
+			err := config.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConfig_CheckDevice(t *testing.T) {
+	originalListDevices := listDevices
+	defer func() { listDevices = originalListDevices }()
+
+	available := []recording.Device{
+		{ID: 42, Name: "alsa_input.usb-headset", Description: "USB Headset", Default: true},
+	}
+
+	tests := []struct {
+		name    string
+		device  string
+		listErr error
+		wantErr bool
+	}{
+		{"default device", "", nil, false},
+		{"existing device by name", "alsa_input.usb-headset", nil, false},
+		{"existing device by id", "42", nil, false},
+		{"missing device", "alsa_input.unplugged", nil, true},
+		{"enumeration unavailable", "alsa_input.unplugged", fmt.Errorf("pw-dump not found"), false},
+	}
+
+	t.Run("non-PipeWire backend skips check", func(t *testing.T) {
+		listDevices = func(ctx context.Context) ([]recording.Device, error) {
+			return available, nil
+		}
+		config := createTestConfig()
+		config.Recording.Backends = []string{"arecord"}
+		config.Recording.Device = "hw:0"
+		if err := config.CheckDevice(); err != nil {
+			t.Errorf("CheckDevice() error = %v", err)
+		}
+	})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			listDevices = func(ctx context.Context) ([]recording.Device, error) {
+				return available, tt.listErr
+			}
+
+			config := createTestConfig()
+			config.Recording.Device = tt.device
+			err := config.CheckDevice()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CheckDevice() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			// Without fallback devices Validate rejects what CheckDevice reports
+			if err := config.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			// With them an unplugged device is only a warning
+			config.Recording.FallbackDevices = []string{"alsa_input.usb-headset"}
+			if err := config.Validate(); err != nil {
+				t.Errorf("Validate() with fallback_devices error = %v", err)
+			}
+		})
+	}
+}
+
 func TestConfig_Validate_InjectionTimeouts(t *testing.T) {
 	config := &Config{
 		Recording: RecordingConfig{
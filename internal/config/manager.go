@@ -37,6 +37,11 @@ func NewManager() (*Manager, error) {
 	if err := config.Validate(); err != nil {
 		log.Printf("Config manager: validation warning: %v", err)
 	}
+	if len(config.Recording.FallbackDevices) > 0 {
+		if err := config.CheckDevice(); err != nil {
+			log.Printf("Config manager: warning: %v", err)
+		}
+	}
 
 	m := &Manager{
 		config:        config,
@@ -147,6 +152,11 @@ func (m *Manager) reloadConfig() {
 		log.Printf("Config manager: invalid config after reload: %v", err)
 		return
 	}
+	if len(newConfig.Recording.FallbackDevices) > 0 {
+		if err := newConfig.CheckDevice(); err != nil {
+			log.Printf("Config manager: warning: %v", err)
+		}
+	}
 
 	m.mu.Lock()
 	m.config = newConfig
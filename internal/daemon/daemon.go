@@ -8,17 +8,32 @@ import (
 	"net"
 	"os"
 	"os/signal"
+	"strings"
 	"sync"
 	"syscall"
+	"time"
 
+	"github.com/leonardotrapani/hyprvoice/internal/archive"
 	"github.com/leonardotrapani/hyprvoice/internal/bus"
 	"github.com/leonardotrapani/hyprvoice/internal/config"
 	"github.com/leonardotrapani/hyprvoice/internal/notify"
 	"github.com/leonardotrapani/hyprvoice/internal/pipeline"
+	"github.com/leonardotrapani/hyprvoice/internal/recording"
+	"github.com/leonardotrapani/hyprvoice/internal/transcriber"
 )
 
+// levelInterval is how often the level command reports the input level
+const levelInterval = 50 * time.Millisecond
+
+// archivePruneInterval is how often the daemon enforces archive retention
+const archivePruneInterval = time.Hour
+
 type Daemon struct {
-	mu        sync.RWMutex
+	mu sync.RWMutex
+	// controlMu serializes commands that change the recording state, so a
+	// start racing a stop from a key press/release pair sees a settled status
+	controlMu sync.Mutex
+
 	notifier  notify.Notifier
 	configMgr *config.Manager
 
@@ -26,6 +41,9 @@ type Daemon struct {
 	cancel context.CancelFunc
 
 	pipeline pipeline.Pipeline
+	warm     *recording.WarmCapture
+	archive  *archive.Archive
+	server   *transcriber.WhisperServer
 
 	wg sync.WaitGroup
 }
@@ -59,6 +77,11 @@ func (d *Daemon) onConfigReload() {
 	d.notifier = notify.NewNotifier(conf.Notifications.Type, conf.Notifications.Messages.Resolve())
 	d.mu.Unlock()
 
+	d.stopWarmCapture()
+	d.startWarmCapture()
+	d.loadArchive()
+	d.loadWhisperServer()
+
 	d.notifier.Send(notify.MsgConfigReloaded)
 }
 
@@ -71,6 +94,157 @@ func (d *Daemon) status() pipeline.Status {
 	return d.pipeline.Status()
 }
 
+func (d *Daemon) captureStats() recording.CaptureStats {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.pipeline == nil {
+		return recording.CaptureStats{}
+	}
+	return d.pipeline.CaptureStats()
+}
+
+// startWarmCapture opens the shared microphone capture when warm mode is enabled
+func (d *Daemon) startWarmCapture() {
+	conf := d.configMgr.GetConfig()
+	if !conf.Recording.Warm {
+		return
+	}
+
+	warm := recording.NewWarmCapture(conf.ToRecordingConfig())
+	if err := warm.Start(d.ctx); err != nil {
+		log.Printf("Warning: failed to start warm capture, recordings will start cold: %v", err)
+		return
+	}
+
+	d.mu.Lock()
+	d.warm = warm
+	d.mu.Unlock()
+}
+
+func (d *Daemon) stopWarmCapture() {
+	d.mu.Lock()
+	warm := d.warm
+	d.warm = nil
+	d.mu.Unlock()
+
+	if warm != nil {
+		warm.Stop()
+	}
+}
+
+// loadArchive sets up the session archive from the current config and applies its retention limits
+func (d *Daemon) loadArchive() {
+	conf := d.configMgr.GetConfig()
+
+	var a *archive.Archive
+	if conf.Archive.Enabled {
+		archiveConfig, err := conf.ToArchiveConfig()
+		if err != nil {
+			log.Printf("Warning: failed to set up session archive: %v", err)
+		} else {
+			a = archive.New(archiveConfig)
+			log.Printf("Archive: saving sessions to %s", archiveConfig.Dir)
+		}
+	}
+
+	d.mu.Lock()
+	d.archive = a
+	d.mu.Unlock()
+
+	d.pruneArchive()
+}
+
+func (d *Daemon) pruneArchive() {
+	d.mu.RLock()
+	a := d.archive
+	d.mu.RUnlock()
+
+	if a == nil {
+		return
+	}
+	if err := a.Prune(); err != nil {
+		log.Printf("Archive: retention failed: %v", err)
+	}
+}
+
+// enforceArchiveRetention prunes the archive periodically so age limits apply between sessions
+func (d *Daemon) enforceArchiveRetention() {
+	ticker := time.NewTicker(archivePruneInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			d.pruneArchive()
+		case <-d.ctx.Done():
+			return
+		}
+	}
+}
+
+// loadWhisperServer sets up the managed whisper.cpp server from the current
+// config. A running server is kept when its settings did not change, so a
+// config reload does not reload the model.
+func (d *Daemon) loadWhisperServer() {
+	conf := d.configMgr.GetConfig()
+	serverConfig, managed := conf.ToWhisperServerConfig()
+
+	d.mu.Lock()
+	old := d.server
+	if old != nil && managed && old.Config() == serverConfig {
+		d.mu.Unlock()
+		return
+	}
+	d.server = nil
+	if managed {
+		d.server = transcriber.NewWhisperServer(serverConfig)
+		log.Printf("Daemon: whisper.cpp server on port %d is started on first use", serverConfig.Port)
+	}
+	d.mu.Unlock()
+
+	if old != nil {
+		old.Stop()
+	}
+}
+
+func (d *Daemon) stopWhisperServer() {
+	d.mu.Lock()
+	server := d.server
+	d.server = nil
+	d.mu.Unlock()
+
+	if server != nil {
+		server.Stop()
+	}
+}
+
+// whisperServerState returns the managed server state, empty when there is none
+func (d *Daemon) whisperServerState() string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.server == nil {
+		return ""
+	}
+	return d.server.State()
+}
+
+func (d *Daemon) pipelineOptions() []pipeline.Option {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	var opts []pipeline.Option
+	if d.warm != nil {
+		opts = append(opts, pipeline.WithWarmCapture(d.warm))
+	}
+	if d.archive != nil {
+		opts = append(opts, pipeline.WithArchive(d.archive))
+	}
+	if d.server != nil {
+		opts = append(opts, pipeline.WithWhisperServer(d.server))
+	}
+	return opts
+}
+
 func (d *Daemon) stopPipeline() {
 	d.mu.Lock()
 	p := d.pipeline
@@ -105,6 +279,15 @@ func (d *Daemon) Run() error {
 	}
 	defer d.configMgr.Stop()
 
+	d.startWarmCapture()
+	defer d.stopWarmCapture()
+
+	d.loadArchive()
+	go d.enforceArchiveRetention()
+
+	d.loadWhisperServer()
+	defer d.stopWhisperServer()
+
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGTERM, syscall.SIGINT)
 	defer signal.Stop(sigCh)
@@ -155,17 +338,64 @@ func (d *Daemon) handle(c net.Conn) {
 		return
 	}
 	cmd := line[0]
+	arg := strings.TrimSpace(line[1:])
 
 	switch cmd {
 	case 't':
-		d.toggle()
+		if err := checkSource(arg); err != nil {
+			fmt.Fprintf(c, "ERR %v\n", err)
+			return
+		}
+		if err := d.toggle(arg); err != nil {
+			fmt.Fprintf(c, "ERR %v\n", err)
+			return
+		}
 		fmt.Fprint(c, "OK toggled\n")
+	case 'b':
+		if err := checkSource(arg); err != nil {
+			fmt.Fprintf(c, "ERR %v\n", err)
+			return
+		}
+		result, err := d.start(arg)
+		if err != nil {
+			fmt.Fprintf(c, "ERR %v\n", err)
+			return
+		}
+		fmt.Fprintf(c, "OK %s\n", result)
+	case 'e':
+		result, err := d.stop()
+		if err != nil {
+			fmt.Fprintf(c, "ERR %v\n", err)
+			return
+		}
+		fmt.Fprintf(c, "OK %s\n", result)
 	case 'c':
 		d.cancelPipeline()
 		fmt.Fprint(c, "OK cancelled\n")
 	case 's':
 		status := d.status()
-		fmt.Fprintf(c, "STATUS status=%s\n", status)
+		reply := fmt.Sprintf("STATUS status=%s", status)
+		if status != pipeline.Idle {
+			reply += fmt.Sprintf(" %s", d.captureStats())
+		}
+		if state := d.whisperServerState(); state != "" {
+			reply += fmt.Sprintf(" whisper_server=%s", state)
+		}
+		fmt.Fprintf(c, "%s\n", reply)
+	case 'p':
+		if err := d.pause(); err != nil {
+			fmt.Fprintf(c, "ERR %v\n", err)
+			return
+		}
+		fmt.Fprint(c, "OK paused\n")
+	case 'r':
+		if err := d.resume(); err != nil {
+			fmt.Fprintf(c, "ERR %v\n", err)
+			return
+		}
+		fmt.Fprint(c, "OK resumed\n")
+	case 'l':
+		d.streamLevels(c)
 	case 'v':
 		fmt.Fprintf(c, "STATUS proto=%s\n", bus.ProtoVer)
 	case 'q':
@@ -177,33 +407,63 @@ func (d *Daemon) handle(c net.Conn) {
 	}
 }
 
-func (d *Daemon) toggle() {
-	conf := d.configMgr.GetConfig()
-	switch d.status() {
-	case pipeline.Idle:
-		p := pipeline.New(conf)
-		p.Run(d.ctx)
+// streamLevels writes the live input level until the recording ends or the
+// client disconnects. A paused recording keeps the stream open with zero levels.
+func (d *Daemon) streamLevels(c net.Conn) {
+	ticker := time.NewTicker(levelInterval)
+	defer ticker.Stop()
 
-		d.mu.Lock()
-		d.pipeline = p
-		d.mu.Unlock()
+	for {
+		d.mu.RLock()
+		p := d.pipeline
+		d.mu.RUnlock()
+
+		if p == nil || !inSession(p.Status()) {
+			fmt.Fprintf(c, "OK stopped status=%s\n", d.status())
+			return
+		}
 
-		go d.notifier.Send(notify.MsgRecordingStarted)
-		go d.monitorPipelineErrors(p)
+		var err error
+		if p.Status() == pipeline.Paused {
+			_, err = fmt.Fprintf(c, "LEVEL rms=0.0000 peak=0.0000 status=%s\n", pipeline.Paused)
+		} else {
+			level := p.Level()
+			_, err = fmt.Fprintf(c, "LEVEL rms=%.4f peak=%.4f\n", level.RMS, level.Peak)
+		}
+		if err != nil {
+			return
+		}
+
+		select {
+		case <-ticker.C:
+		case <-d.ctx.Done():
+			return
+		}
+	}
+}
+
+// inSession reports whether a recording is running, paused or not
+func inSession(status pipeline.Status) bool {
+	return status == pipeline.Recording || status == pipeline.Transcribing || status == pipeline.Paused
+}
+
+// toggle starts a recording of source (the configured one when empty) or
+// moves the current session on to its next step
+func (d *Daemon) toggle(source string) error {
+	d.controlMu.Lock()
+	defer d.controlMu.Unlock()
+
+	switch d.status() {
+	case pipeline.Idle:
+		d.startPipeline(source)
 
 	case pipeline.Recording:
 		d.stopPipeline()
 		go d.notifier.Send(notify.MsgRecordingAborted)
 
-	case pipeline.Transcribing:
-		d.mu.RLock()
-		if d.pipeline != nil {
-			actionChan := d.pipeline.GetActionCh()
-			log.Printf("Daemon: Sending inject action to pipeline")
-			d.mu.RUnlock()
-			actionChan <- pipeline.Inject
-		} else {
-			d.mu.RUnlock()
+	case pipeline.Transcribing, pipeline.Paused:
+		if err := d.sendAction(pipeline.Inject); err != nil {
+			return err
 		}
 		go d.notifier.Send(notify.MsgTranscribing)
 
@@ -211,6 +471,130 @@ func (d *Daemon) toggle() {
 		d.stopPipeline()
 		go d.notifier.Send(notify.MsgInjectionAborted)
 	}
+	return nil
+}
+
+// start begins a recording unless one is already running. Unlike toggle it
+// never ends a session, so it is safe to bind to a key press. It returns the
+// result for the client.
+func (d *Daemon) start(source string) (string, error) {
+	d.controlMu.Lock()
+	defer d.controlMu.Unlock()
+
+	switch status := d.status(); status {
+	case pipeline.Idle:
+		d.startPipeline(source)
+		return "started", nil
+	case pipeline.Injecting:
+		return "", fmt.Errorf("busy status=%s", status)
+	default:
+		log.Printf("Daemon: Start requested but already %s, ignoring", status)
+		return fmt.Sprintf("already_recording status=%s", status), nil
+	}
+}
+
+// stop finishes the current recording and transcribes it. Unlike toggle it
+// never starts or aborts a session, so it is safe to bind to a key release.
+// It returns the result for the client.
+func (d *Daemon) stop() (string, error) {
+	d.controlMu.Lock()
+	defer d.controlMu.Unlock()
+
+	switch status := d.status(); status {
+	case pipeline.Recording:
+		// Released before the microphone opened, there is nothing to transcribe
+		d.stopPipeline()
+		go d.notifier.Send(notify.MsgRecordingAborted)
+		return "aborted", nil
+	case pipeline.Transcribing, pipeline.Paused:
+		if err := d.sendAction(pipeline.Inject); err != nil {
+			return "", err
+		}
+		go d.notifier.Send(notify.MsgTranscribing)
+		return "stopped", nil
+	default:
+		log.Printf("Daemon: Stop requested but not recording (%s), ignoring", status)
+		return fmt.Sprintf("not_recording status=%s", status), nil
+	}
+}
+
+// startPipeline begins a recording, of source instead of the configured
+// recording.source when it is set
+func (d *Daemon) startPipeline(source string) {
+	conf := d.configMgr.GetConfig()
+	if source != "" {
+		conf.Recording.Source = source
+	}
+	p := pipeline.New(conf, d.pipelineOptions()...)
+	p.Run(d.ctx)
+
+	d.mu.Lock()
+	d.pipeline = p
+	d.mu.Unlock()
+
+	go d.notifier.Send(notify.MsgRecordingStarted)
+	go d.monitorPipelineErrors(p)
+}
+
+// checkSource validates a recording source requested by a client
+func checkSource(source string) error {
+	switch source {
+	case "", recording.InputMic, recording.InputMonitor, recording.InputMixed:
+		return nil
+	default:
+		return fmt.Errorf("invalid_source=%q", source)
+	}
+}
+
+// pause stops keeping audio without ending the recording session
+func (d *Daemon) pause() error {
+	d.controlMu.Lock()
+	defer d.controlMu.Unlock()
+
+	if status := d.status(); status != pipeline.Transcribing {
+		return fmt.Errorf("not_recording status=%s", status)
+	}
+	if err := d.sendAction(pipeline.Pause); err != nil {
+		return err
+	}
+	go d.notifier.Send(notify.MsgRecordingPaused)
+	return nil
+}
+
+// resume keeps audio again after pause
+func (d *Daemon) resume() error {
+	d.controlMu.Lock()
+	defer d.controlMu.Unlock()
+
+	if status := d.status(); status != pipeline.Paused {
+		return fmt.Errorf("not_paused status=%s", status)
+	}
+	if err := d.sendAction(pipeline.Resume); err != nil {
+		return err
+	}
+	go d.notifier.Send(notify.MsgRecordingResumed)
+	return nil
+}
+
+// sendAction hands action to the pipeline without waiting. An action already
+// queued, such as an Inject from auto-stop or the timeout, leaves no room and
+// the pipeline is reported busy rather than blocking the control commands.
+func (d *Daemon) sendAction(action pipeline.Action) error {
+	d.mu.RLock()
+	p := d.pipeline
+	d.mu.RUnlock()
+	if p == nil {
+		return nil
+	}
+
+	select {
+	case p.GetActionCh() <- action:
+		log.Printf("Daemon: Sent %s action to pipeline", action)
+		return nil
+	default:
+		log.Printf("Daemon: Pipeline is not accepting actions, dropping %s", action)
+		return fmt.Errorf("busy status=%s", p.Status())
+	}
 }
 
 func (d *Daemon) cancelPipeline() {
@@ -225,8 +609,11 @@ func (d *Daemon) cancelPipeline() {
 
 func (d *Daemon) monitorPipelineErrors(p pipeline.Pipeline) {
 	errorCh := p.GetErrorCh()
+	notifyCh := p.GetNotifyCh()
 	for {
 		select {
+		case mt := <-notifyCh:
+			d.notifier.Send(mt)
 		case pipelineErr := <-errorCh:
 			message := pipelineErr.Message
 
@@ -1,15 +1,20 @@
 package daemon
 
 import (
+	"bufio"
 	"context"
 	"io"
 	"net"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/leonardotrapani/hyprvoice/internal/notify"
 	"github.com/leonardotrapani/hyprvoice/internal/pipeline"
+	"github.com/leonardotrapani/hyprvoice/internal/recording"
+	"github.com/leonardotrapani/hyprvoice/internal/transcriber"
 )
 
 func TestNew(t *testing.T) {
@@ -168,12 +173,12 @@ type = "log"`
 	}
 
 	// Test toggle from idle to recording
-	daemon.toggle()
+	daemon.toggle("")
 	status := daemon.status()
 	t.Logf("Status after first toggle = %s", status)
 
 	// Test toggle from recording to idle (abort)
-	daemon.toggle()
+	daemon.toggle("")
 	status = daemon.status()
 	t.Logf("Status after second toggle = %s", status)
 }
@@ -241,6 +246,20 @@ type = "log"`
 			t.Errorf("handle() response = %q, want %q", response, "STATUS status=idle\n")
 		}
 	})
+
+	t.Run("status_with_managed_server", func(t *testing.T) {
+		daemon.server = transcriber.NewWhisperServer(transcriber.WhisperServerConfig{Port: 8025})
+		defer func() { daemon.server = nil }()
+
+		mockConn := &MockConn{readData: []byte("s\n")}
+		daemon.wg.Add(1)
+		daemon.handle(mockConn)
+
+		want := "STATUS status=idle whisper_server=stopped\n"
+		if response := string(mockConn.writeData); response != want {
+			t.Errorf("handle() response = %q, want %q", response, want)
+		}
+	})
 }
 
 // MockConn implements net.Conn for testing
@@ -434,6 +453,11 @@ type = "log"`
 		command  string
 		expected string
 	}{
+		{"level_command_idle", "l\n", "OK stopped status=idle\n"},
+		{"pause_command_idle", "p\n", "ERR not_recording status=idle\n"},
+		{"resume_command_idle", "r\n", "ERR not_paused status=idle\n"},
+		{"toggle_invalid_source", "t speakers\n", "ERR invalid_source=\"speakers\"\n"},
+		{"start_invalid_source", "b speakers\n", "ERR invalid_source=\"speakers\"\n"},
 		{"toggle_command", "t\n", "OK toggled\n"},
 		{"version_command", "v\n", "STATUS proto="},
 		{"quit_command", "q\n", "OK quitting\n"},
@@ -470,13 +494,187 @@ type = "log"`
 	}
 }
 
+func TestDaemon_StartStop(t *testing.T) {
+	tempDir := t.TempDir()
+	originalConfigDir := os.Getenv("XDG_CONFIG_HOME")
+	os.Setenv("XDG_CONFIG_HOME", tempDir)
+	defer func() {
+		if originalConfigDir == "" {
+			os.Unsetenv("XDG_CONFIG_HOME")
+		} else {
+			os.Setenv("XDG_CONFIG_HOME", originalConfigDir)
+		}
+	}()
+
+	configPath := filepath.Join(tempDir, "hyprvoice", "config.toml")
+	os.MkdirAll(filepath.Dir(configPath), 0755)
+	configContent := `[transcription]
+provider = "openai"
+api_key = "test-key"
+model = "whisper-1"
+
+[notifications]
+enabled = true
+type = "log"`
+	os.WriteFile(configPath, []byte(configContent), 0644)
+
+	daemon, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create daemon: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		status       pipeline.Status
+		command      string
+		expected     string
+		wantAction   bool
+		wantPipeline bool
+	}{
+		{"stop_idle", pipeline.Idle, "e\n", "OK not_recording status=idle\n", false, false},
+		{"stop_recording", pipeline.Transcribing, "e\n", "OK stopped\n", true, true},
+		{"stop_paused", pipeline.Paused, "e\n", "OK stopped\n", true, true},
+		{"stop_injecting", pipeline.Injecting, "e\n", "OK not_recording status=injecting\n", false, true},
+		{"stop_before_capture", pipeline.Recording, "e\n", "OK aborted\n", false, false},
+		{"start_recording", pipeline.Transcribing, "b\n", "OK already_recording status=transcribing\n", false, true},
+		{"start_paused", pipeline.Paused, "b\n", "OK already_recording status=paused\n", false, true},
+		{"start_injecting", pipeline.Injecting, "b\n", "ERR busy status=injecting\n", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &MockPipeline{status: tt.status, actions: make(chan pipeline.Action, 1)}
+			daemon.mu.Lock()
+			daemon.pipeline = mock
+			if tt.status == pipeline.Idle {
+				daemon.pipeline = nil
+			}
+			daemon.mu.Unlock()
+
+			mockConn := &MockConn{readData: []byte(tt.command)}
+			daemon.wg.Add(1)
+			daemon.handle(mockConn)
+
+			if response := string(mockConn.writeData); response != tt.expected {
+				t.Errorf("handle() response = %q, want %q", response, tt.expected)
+			}
+
+			select {
+			case action := <-mock.actions:
+				if !tt.wantAction {
+					t.Errorf("unexpected %s action", action)
+				} else if action != pipeline.Inject {
+					t.Errorf("action = %s, want %s", action, pipeline.Inject)
+				}
+			default:
+				if tt.wantAction {
+					t.Error("expected an inject action")
+				}
+			}
+
+			daemon.mu.RLock()
+			hasPipeline := daemon.pipeline != nil
+			daemon.mu.RUnlock()
+			if tt.status != pipeline.Idle && hasPipeline != tt.wantPipeline {
+				t.Errorf("pipeline kept = %v, want %v", hasPipeline, tt.wantPipeline)
+			}
+		})
+	}
+
+	// An Inject queued by auto-stop or the timeout must not block commands
+	for _, command := range []string{"e\n", "t\n", "p\n"} {
+		t.Run("busy_"+strings.TrimSpace(command), func(t *testing.T) {
+			mock := &MockPipeline{status: pipeline.Transcribing, actions: make(chan pipeline.Action, 1)}
+			mock.actions <- pipeline.Inject
+			daemon.mu.Lock()
+			daemon.pipeline = mock
+			daemon.mu.Unlock()
+
+			mockConn := &MockConn{readData: []byte(command)}
+			done := make(chan struct{})
+			daemon.wg.Add(1)
+			go func() {
+				daemon.handle(mockConn)
+				close(done)
+			}()
+			select {
+			case <-done:
+			case <-time.After(time.Second):
+				t.Fatal("handle() blocked on a full action channel")
+			}
+
+			if response, want := string(mockConn.writeData), "ERR busy status=transcribing\n"; response != want {
+				t.Errorf("handle() response = %q, want %q", response, want)
+			}
+		})
+	}
+}
+
+func TestDaemon_StreamLevels_Paused(t *testing.T) {
+	daemon := &Daemon{ctx: context.Background()}
+	daemon.pipeline = &MockPipeline{status: pipeline.Paused}
+
+	server, client := net.Pipe()
+	defer client.Close()
+	go func() {
+		daemon.streamLevels(server)
+		server.Close()
+	}()
+
+	reader := bufio.NewReader(client)
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		t.Fatalf("ReadString() error = %v", err)
+	}
+	if want := "LEVEL rms=0.0000 peak=0.0000 status=paused\n"; line != want {
+		t.Errorf("paused line = %q, want %q", line, want)
+	}
+
+	// The stream only ends once the session does
+	daemon.mu.Lock()
+	daemon.pipeline = nil
+	daemon.mu.Unlock()
+	for {
+		line, err = reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("stream closed without a final reply: %v", err)
+		}
+		if strings.HasPrefix(line, "OK ") {
+			break
+		}
+	}
+	if want := "OK stopped status=idle\n"; line != want {
+		t.Errorf("final line = %q, want %q", line, want)
+	}
+}
+
 // MockPipeline implements pipeline.Pipeline for testing
-type MockPipeline struct{}
+type MockPipeline struct {
+	status  pipeline.Status
+	actions chan pipeline.Action
+}
 
 func (m *MockPipeline) Run(ctx context.Context) {}
 func (m *MockPipeline) Stop()                   {}
-func (m *MockPipeline) Status() pipeline.Status { return pipeline.Idle }
+func (m *MockPipeline) Status() pipeline.Status {
+	if m.status == "" {
+		return pipeline.Idle
+	}
+	return m.status
+}
 func (m *MockPipeline) GetErrorCh() <-chan pipeline.PipelineError {
 	return make(chan pipeline.PipelineError)
 }
-func (m *MockPipeline) GetActionCh() chan<- pipeline.Action { return make(chan pipeline.Action) }
+func (m *MockPipeline) GetActionCh() chan<- pipeline.Action {
+	if m.actions != nil {
+		return m.actions
+	}
+	return make(chan pipeline.Action)
+}
+func (m *MockPipeline) GetNotifyCh() <-chan notify.MessageType {
+	return make(chan notify.MessageType)
+}
+func (m *MockPipeline) Level() recording.Level { return recording.Level{} }
+func (m *MockPipeline) CaptureStats() recording.CaptureStats {
+	return recording.CaptureStats{}
+}
@@ -10,6 +10,11 @@ const (
 	MsgOperationCancelled
 	MsgRecordingAborted
 	MsgInjectionAborted
+	MsgRecordingPaused
+	MsgRecordingResumed
+	MsgTimeoutWarning
+	MsgDeviceSwitched
+	MsgProviderFallback
 )
 
 // MessageDef defines a message type with its config key and defaults
@@ -29,6 +34,11 @@ var MessageDefs = []MessageDef{
 	{MsgOperationCancelled, "operation_cancelled", "Hyprvoice", "Operation Cancelled", false},
 	{MsgRecordingAborted, "recording_aborted", "", "Recording Aborted", true},
 	{MsgInjectionAborted, "injection_aborted", "", "Injection Aborted", true},
+	{MsgRecordingPaused, "recording_paused", "Hyprvoice", "Recording Paused", false},
+	{MsgRecordingResumed, "recording_resumed", "Hyprvoice", "Recording Resumed", false},
+	{MsgTimeoutWarning, "timeout_warning", "Hyprvoice", "Recording Time Almost Up", false},
+	{MsgDeviceSwitched, "device_switched", "Hyprvoice", "Microphone Disconnected... Switched Input", false},
+	{MsgProviderFallback, "provider_fallback", "Hyprvoice", "Transcribed with Fallback Provider", false},
 }
 
 // Message is a resolved message ready for display
@@ -0,0 +1,140 @@
+package pipeline
+
+import (
+	"context"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/leonardotrapani/hyprvoice/internal/injection"
+)
+
+// liveTyper types finalized transcript segments while the recording is still
+// running. Only typing backends are used, as every segment would replace the
+// previous one on the clipboard; whatever could not be typed live is left to
+// the final injection.
+type liveTyper struct {
+	ctx      context.Context
+	injector injection.Injector
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	pending []string
+	typed   []string // segments typed so far, in order
+	failed  bool     // a segment could not be typed, the rest waits for the final injection
+	closed  bool
+	done    chan struct{}
+}
+
+// newLiveTyper starts typing segments passed to add until finish is called or
+// ctx is cancelled. It returns nil when no typing backend is configured.
+func newLiveTyper(ctx context.Context, config injection.Config) *liveTyper {
+	var backends []string
+	for _, backend := range config.Backends {
+		if backend != "clipboard" {
+			backends = append(backends, backend)
+		}
+	}
+	if len(backends) == 0 {
+		log.Printf("Pipeline: live typing needs ydotool or wtype, typing the transcript at the end instead")
+		return nil
+	}
+	config.Backends = backends
+	return startLiveTyper(ctx, injection.NewInjector(config))
+}
+
+func startLiveTyper(ctx context.Context, injector injection.Injector) *liveTyper {
+	l := &liveTyper{
+		ctx:      ctx,
+		injector: injector,
+		done:     make(chan struct{}),
+	}
+	l.cond = sync.NewCond(&l.mu)
+	go l.run()
+	return l
+}
+
+// add queues a finalized segment for typing
+func (l *liveTyper) add(text string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return
+	}
+	l.pending = append(l.pending, text)
+	l.cond.Signal()
+}
+
+// finish waits until the queued segments are typed. Once the session is
+// cancelled queued segments are dropped, so only what was already typed stays.
+func (l *liveTyper) finish() {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	l.closed = true
+	l.cond.Signal()
+	l.mu.Unlock()
+	<-l.done
+}
+
+func (l *liveTyper) run() {
+	defer close(l.done)
+
+	for {
+		l.mu.Lock()
+		for len(l.pending) == 0 && !l.closed {
+			l.cond.Wait()
+		}
+		if len(l.pending) == 0 {
+			l.mu.Unlock()
+			return
+		}
+		text := l.pending[0]
+		l.pending = l.pending[1:]
+		skip := l.failed || l.ctx.Err() != nil
+		separator := ""
+		if len(l.typed) > 0 {
+			separator = " "
+		}
+		l.mu.Unlock()
+
+		if skip {
+			continue
+		}
+
+		// A segment that started typing is finished even if the session is
+		// cancelled meanwhile, so no half segment is left behind
+		err := l.injector.Inject(context.WithoutCancel(l.ctx), separator+text)
+
+		l.mu.Lock()
+		if err != nil {
+			log.Printf("Pipeline: live typing failed, the rest is typed at the end: %v", err)
+			l.failed = true
+		} else {
+			l.typed = append(l.typed, text)
+		}
+		l.mu.Unlock()
+	}
+}
+
+// remainder returns the part of the final transcript that was not typed
+// live, with the space that separates it from the typed text
+func (l *liveTyper) remainder(final string) string {
+	l.mu.Lock()
+	typed := strings.Join(l.typed, " ")
+	l.mu.Unlock()
+
+	if typed == "" {
+		return final
+	}
+	rest, ok := strings.CutPrefix(final, typed)
+	if !ok {
+		log.Printf("Pipeline: live text does not match the final transcript, not typing the rest")
+		return ""
+	}
+	if rest = strings.TrimSpace(rest); rest == "" {
+		return ""
+	}
+	return " " + rest
+}
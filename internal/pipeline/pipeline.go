@@ -2,12 +2,16 @@ package pipeline
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"sync"
 	"sync/atomic"
+	"time"
 
+	"github.com/leonardotrapani/hyprvoice/internal/archive"
 	"github.com/leonardotrapani/hyprvoice/internal/config"
 	"github.com/leonardotrapani/hyprvoice/internal/injection"
+	"github.com/leonardotrapani/hyprvoice/internal/notify"
 	"github.com/leonardotrapani/hyprvoice/internal/recording"
 	"github.com/leonardotrapani/hyprvoice/internal/transcriber"
 )
@@ -26,11 +30,14 @@ const (
 	Recording    Status = "recording"
 	Transcribing Status = "transcribing"
 	Injecting    Status = "injecting"
+	Paused       Status = "paused"
 )
 
 const (
 	Inject Action = "inject"
 	Cancel Action = "cancel"
+	Pause  Action = "pause"
+	Resume Action = "resume"
 )
 
 type Pipeline interface {
@@ -39,28 +46,72 @@ type Pipeline interface {
 	Status() Status
 	GetActionCh() chan<- Action
 	GetErrorCh() <-chan PipelineError
+	GetNotifyCh() <-chan notify.MessageType
+	Level() recording.Level
+	CaptureStats() recording.CaptureStats
+}
+
+// Option configures shared resources a pipeline borrows from the daemon
+type Option func(*pipeline)
+
+// WithArchive saves every finished session to the archive
+func WithArchive(a *archive.Archive) Option {
+	return func(p *pipeline) {
+		p.archive = a
+	}
+}
+
+// WithWarmCapture makes the pipeline record from an already running warm capture
+func WithWarmCapture(warm *recording.WarmCapture) Option {
+	return func(p *pipeline) {
+		p.warm = warm
+	}
+}
+
+// WithWhisperServer transcribes through the daemon's managed whisper.cpp server
+func WithWhisperServer(server *transcriber.WhisperServer) Option {
+	return func(p *pipeline) {
+		p.whisperServer = server
+	}
 }
 
 type pipeline struct {
 	status   Status
 	actionCh chan Action
 	errorCh  chan PipelineError
+	notifyCh chan notify.MessageType
 	config   *config.Config
+	warm     *recording.WarmCapture
+	archive  *archive.Archive
+
+	whisperServer *transcriber.WhisperServer
+
+	startedAt time.Time
+	audioMu   sync.Mutex
+	audio     []byte // session audio kept for the archive
 
 	mu       sync.RWMutex
+	level    recording.Level
+	recorder *recording.Recorder
 	wg       sync.WaitGroup
 	cancel   context.CancelFunc
 	stopOnce sync.Once
 
 	running atomic.Bool
+	paused  atomic.Bool // audio is discarded while set
 }
 
-func New(cfg *config.Config) Pipeline {
-	return &pipeline{
+func New(cfg *config.Config, opts ...Option) Pipeline {
+	p := &pipeline{
 		actionCh: make(chan Action, 1),
 		errorCh:  make(chan PipelineError, 10),
+		notifyCh: make(chan notify.MessageType, 10),
 		config:   cfg,
 	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
 }
 func (p *pipeline) Run(ctx context.Context) {
 	if !p.running.CompareAndSwap(false, true) {
@@ -68,7 +119,12 @@ func (p *pipeline) Run(ctx context.Context) {
 		return
 	}
 
-	runCtx, cancel := context.WithTimeout(ctx, p.config.Recording.Timeout)
+	// Aborting at the timeout cancels the whole run, otherwise run() finishes
+	// the recording itself when the limit is reached
+	runCtx, cancel := context.WithCancel(ctx)
+	if p.abortOnTimeout() {
+		runCtx, cancel = context.WithTimeout(ctx, p.config.Recording.Timeout)
+	}
 	p.setCancel(cancel)
 
 	p.wg.Add(1)
@@ -84,8 +140,18 @@ func (p *pipeline) run(ctx context.Context) {
 
 	log.Printf("Pipeline: Starting recording")
 	p.setStatus(Recording)
+	p.startedAt = time.Now()
 
-	recorder := recording.NewRecorder(p.config.ToRecordingConfig())
+	var recorder *recording.Recorder
+	if p.warm != nil && p.warm.Records(p.config.Recording.Source) {
+		recorder = recording.NewWarmRecorder(p.config.ToRecordingConfig(), p.warm)
+	} else {
+		recorder = recording.NewRecorder(p.config.ToRecordingConfig())
+	}
+	recorder.OnDeviceChange(func(from, to string) {
+		p.sendNotify(notify.MsgDeviceSwitched)
+	})
+	p.setRecorder(recorder)
 	frameCh, rErrCh, err := recorder.Start(ctx)
 
 	if err != nil {
@@ -96,17 +162,28 @@ func (p *pipeline) run(ctx context.Context) {
 
 	defer recorder.Stop()
 
-	t, err := transcriber.NewTranscriber(p.config.ToTranscriberConfig())
+	transCfg := p.config.ToTranscriberConfig()
+	if p.whisperServer != nil {
+		transCfg.UseServer(p.whisperServer)
+		go p.warmUpWhisperServer(ctx)
+	}
+
+	t, err := transcriber.NewTranscriber(transCfg)
 	if err != nil {
 		log.Printf("Pipeline: Failed to create transcriber: %v", err)
 		p.sendError("Transcription Error", "Failed to create transcriber", err)
 		return
 	}
 
+	// Live typing is finished after the transcriber is stopped, which
+	// finalizes the segments still pending
+	live := p.startLiveTyping(ctx, t)
+	defer live.finish()
+
 	log.Printf("Pipeline: Starting transcriber")
 	p.setStatus(Transcribing)
 
-	tErrCh, err := t.Start(ctx, frameCh)
+	tErrCh, err := t.Start(ctx, p.forwardFrames(ctx, frameCh))
 	if err != nil {
 		log.Printf("Pipeline: Transcriber error: %v", err)
 		p.sendError("Transcription Error", "Failed to start transcriber", err)
@@ -130,19 +207,34 @@ func (p *pipeline) run(ctx context.Context) {
 
 	go func() {
 		for err := range rErrCh {
-			p.sendError("Recording Error", "Recording stream error", err)
+			p.sendError("Recording Error", "Recording stream error", fmt.Errorf("%w (%s)", err, recorder.Stats()))
 		}
 	}()
 
+	warningC, limitC, stopTimers := p.timeoutTimers()
+	defer stopTimers()
+
 	for {
 		select {
-		case <-frameCh:
+		case <-warningC:
+			log.Printf("Pipeline: Recording timeout in %v", p.config.Recording.TimeoutWarning)
+			p.sendNotify(notify.MsgTimeoutWarning)
+
+		case <-limitC:
+			log.Printf("Pipeline: Recording timeout of %v reached, transcribing", p.config.Recording.Timeout)
+			p.sendNotify(notify.MsgTranscribing)
+			p.handleInjectAction(ctx, recorder, t, live)
+			return
 
 		case action := <-p.actionCh:
 			switch action {
 			case Inject:
-				p.handleInjectAction(ctx, recorder, t)
+				p.handleInjectAction(ctx, recorder, t, live)
 				return
+			case Pause:
+				p.handlePauseAction()
+			case Resume:
+				p.handleResumeAction()
 			}
 
 		case <-ctx.Done():
@@ -163,6 +255,37 @@ func (p *pipeline) setStatus(status Status) {
 	p.status = status
 }
 
+// Level returns the loudness of the most recently captured frame
+func (p *pipeline) Level() recording.Level {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.level
+}
+
+func (p *pipeline) setLevel(level recording.Level) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.level = level
+}
+
+// CaptureStats reports frame delivery statistics for the current recording
+func (p *pipeline) CaptureStats() recording.CaptureStats {
+	p.mu.RLock()
+	recorder := p.recorder
+	p.mu.RUnlock()
+
+	if recorder == nil {
+		return recording.CaptureStats{}
+	}
+	return recorder.Stats()
+}
+
+func (p *pipeline) setRecorder(recorder *recording.Recorder) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.recorder = recorder
+}
+
 func (p *pipeline) setCancel(cancel context.CancelFunc) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
@@ -187,6 +310,143 @@ func (p *pipeline) GetErrorCh() <-chan PipelineError {
 	return p.errorCh
 }
 
+func (p *pipeline) GetNotifyCh() <-chan notify.MessageType {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.notifyCh
+}
+
+func (p *pipeline) sendNotify(mt notify.MessageType) {
+	select {
+	case p.notifyCh <- mt:
+	default:
+		log.Printf("Pipeline: Notify channel full, dropping notification %d", mt)
+	}
+}
+
+// warmUpWhisperServer starts the managed server while the user is still
+// talking, so loading the model does not delay the transcript
+func (p *pipeline) warmUpWhisperServer(ctx context.Context) {
+	release, err := p.whisperServer.Acquire(ctx)
+	if err != nil {
+		log.Printf("Pipeline: whisper.cpp server not ready: %v", err)
+		return
+	}
+	release()
+}
+
+// forwardFrames relays recorder frames to the transcriber, running voice
+// activity detection and audio preprocessing on the way when they are enabled.
+// Detection sees the raw audio so its threshold does not depend on the gain.
+func (p *pipeline) forwardFrames(ctx context.Context, frameCh <-chan recording.AudioFrame) <-chan recording.AudioFrame {
+	out := make(chan recording.AudioFrame, p.config.Recording.ChannelBufferSize)
+
+	recCfg := p.config.ToRecordingConfig()
+	transCfg := p.config.ToTranscriberConfig()
+
+	// Everything downstream works on s16 audio in the transcriber's layout
+	converter := recording.NewConverter(recCfg, transCfg.SampleRate, transCfg.Channels)
+	if !converter.Passthrough() {
+		log.Printf("Pipeline: Converting %s %d Hz %d ch audio to s16 %d Hz %d ch",
+			recCfg.Format, recCfg.SampleRate, recCfg.Channels, transCfg.SampleRate, transCfg.Channels)
+	}
+
+	var vad *recording.VAD
+	if recCfg.VAD.Enabled {
+		vad = recording.NewVAD(recCfg.VAD, transCfg.SampleRate, transCfg.Channels)
+	}
+	var dsp *recording.DSP
+	if recCfg.DSP.Enabled() {
+		dsp = recording.NewDSP(recCfg.DSP, transCfg.SampleRate, transCfg.Channels)
+	}
+
+	// process runs one converted frame through the remaining stages
+	process := func(frame recording.AudioFrame) bool {
+		p.setLevel(frame.Level)
+
+		if vad != nil && vad.Process(frame.Data) {
+			p.autoStop()
+		}
+		if dsp != nil {
+			frame.Data = dsp.Process(frame.Data)
+		}
+		if p.archive != nil {
+			p.audioMu.Lock()
+			p.audio = append(p.audio, frame.Data...)
+			p.audioMu.Unlock()
+		}
+
+		select {
+		case out <- frame:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	go func() {
+		defer close(out)
+		for frame := range frameCh {
+			if p.paused.Load() {
+				continue
+			}
+			if !converter.Passthrough() {
+				frame.Data = converter.Convert(frame.Data)
+				frame.Level = recording.ComputeLevel(frame.Data)
+			}
+			if !process(frame) {
+				return
+			}
+		}
+
+		// The resampler holds back the last few samples until the stream ends
+		if tail := converter.Flush(); len(tail) > 0 {
+			process(recording.AudioFrame{Data: tail, Timestamp: time.Now(), Level: recording.ComputeLevel(tail)})
+		}
+	}()
+
+	return out
+}
+
+func (p *pipeline) abortOnTimeout() bool {
+	return p.config.Recording.TimeoutAction == "abort"
+}
+
+// timeoutTimers returns channels that fire timeout_warning before the
+// recording timeout and, unless the run is aborted instead, at the timeout
+func (p *pipeline) timeoutTimers() (warningC, limitC <-chan time.Time, stop func()) {
+	var timers []*time.Timer
+	timeout, warning := p.config.Recording.Timeout, p.config.Recording.TimeoutWarning
+
+	if warning > 0 && warning < timeout {
+		t := time.NewTimer(timeout - warning)
+		timers = append(timers, t)
+		warningC = t.C
+	}
+	if !p.abortOnTimeout() {
+		t := time.NewTimer(timeout)
+		timers = append(timers, t)
+		limitC = t.C
+	}
+
+	return warningC, limitC, func() {
+		for _, t := range timers {
+			t.Stop()
+		}
+	}
+}
+
+// autoStop finishes the recording as if the user had toggled it off
+func (p *pipeline) autoStop() {
+	select {
+	case p.actionCh <- Inject:
+		log.Printf("Pipeline: Trailing silence detected, stopping recording")
+		p.sendNotify(notify.MsgTranscribing)
+	default:
+		log.Printf("Pipeline: Trailing silence detected, but an action is already pending")
+	}
+}
+
 func (p *pipeline) sendError(title, message string, err error) {
 	pipelineErr := PipelineError{
 		Title:   title,
@@ -201,10 +461,47 @@ func (p *pipeline) sendError(title, message string, err error) {
 	}
 }
 
-func (p *pipeline) handleInjectAction(ctx context.Context, recorder *recording.Recorder, t transcriber.Transcriber) {
+// transcriptionErrorMessage tells the user what to do about a failed
+// transcription, falling back to message when the cause is unknown
+func transcriptionErrorMessage(err error, message string) string {
+	switch transcriber.ClassifyError(err) {
+	case transcriber.ErrorAuth:
+		return "Provider rejected the API key, check transcription.api_key"
+	case transcriber.ErrorRateLimit:
+		return "Provider rate limit reached, try again shortly"
+	case transcriber.ErrorServer:
+		return "Provider is having problems, try again later"
+	case transcriber.ErrorNetwork:
+		return "Could not reach the transcription provider"
+	case transcriber.ErrorBadRequest:
+		return "Provider rejected the request, check the model and language"
+	}
+	return message
+}
+
+// startLiveTyping types segments as they are transcribed when injection.live
+// is enabled and the transcriber finishes segments during the recording
+func (p *pipeline) startLiveTyping(ctx context.Context, t transcriber.Transcriber) *liveTyper {
+	if !p.config.Injection.Live {
+		return nil
+	}
+	incremental, ok := t.(transcriber.IncrementalTranscriber)
+	if !ok {
+		log.Printf("Pipeline: transcriber does not produce partial results, live typing disabled")
+		return nil
+	}
+
+	live := newLiveTyper(ctx, p.config.ToInjectionConfig())
+	if live != nil {
+		incremental.OnSegment(live.add)
+	}
+	return live
+}
+
+func (p *pipeline) handleInjectAction(ctx context.Context, recorder *recording.Recorder, t transcriber.Transcriber, live *liveTyper) {
 	status := p.Status()
 
-	if status != Transcribing {
+	if status != Transcribing && status != Paused {
 		log.Printf("Pipeline: Inject action received, but not in transcribing state, ignoring")
 		return
 	}
@@ -213,22 +510,59 @@ func (p *pipeline) handleInjectAction(ctx context.Context, recorder *recording.R
 	p.setStatus(Injecting)
 
 	recorder.Stop()
+	stoppedAt := time.Now()
 
-	if err := t.Stop(ctx); err != nil {
-		p.sendError("Transcription Error", "Failed to stop transcriber during injection", err)
+	session := archive.Session{StartedAt: p.startedAt, RecordingDuration: stoppedAt.Sub(p.startedAt)}
+	defer p.archiveSession(&session)
+
+	stats := recorder.Stats()
+	log.Printf("Pipeline: Recording finished: %s", stats)
+	if stats.Dropped > 0 {
+		p.sendError("Recording Error", "Audio was lost during recording", fmt.Errorf("dropped %d of %d frames (%s)", stats.Dropped, stats.Frames, stats))
+	}
+
+	err := t.Stop(ctx)
+	session.TranscriptionDuration = time.Since(stoppedAt)
+	if err != nil {
+		session.Error = err.Error()
+		p.sendError("Transcription Error", transcriptionErrorMessage(err, "Failed to stop transcriber during injection"), err)
 		return
 	}
 
 	transcriptionText, err := t.GetFinalTranscription()
 	if err != nil {
-		p.sendError("Transcription Error", "Failed to retrieve transcription", err)
+		session.Error = err.Error()
+		p.sendError("Transcription Error", transcriptionErrorMessage(err, "Failed to retrieve transcription"), err)
 		return
 	}
 	log.Printf("Pipeline: Final transcription text: %s", transcriptionText)
+	session.Transcript = transcriptionText
+
+	if reporter, ok := t.(transcriber.ProviderReporter); ok {
+		session.Provider = reporter.Provider()
+		if primary := p.config.ToTranscriberConfig().Provider; session.Provider != primary {
+			log.Printf("Pipeline: %s failed, transcript produced by %s", primary, session.Provider)
+			p.sendNotify(notify.MsgProviderFallback)
+		}
+	}
+
+	if live != nil {
+		live.finish()
+		transcriptionText = live.remainder(transcriptionText)
+		if transcriptionText == "" {
+			log.Printf("Pipeline: Transcript was typed live, nothing left to inject")
+			p.setStatus(Idle)
+			return
+		}
+	}
 
 	injector := injection.NewInjector(p.config.ToInjectionConfig())
 
-	if err := injector.Inject(ctx, transcriptionText); err != nil {
+	injectStart := time.Now()
+	err = injector.Inject(ctx, transcriptionText)
+	session.InjectionDuration = time.Since(injectStart)
+	if err != nil {
+		session.Error = err.Error()
 		p.sendError("Injection Error", "Failed to inject text", err)
 	} else {
 		log.Printf("Pipeline: Text injection completed successfully")
@@ -237,6 +571,65 @@ func (p *pipeline) handleInjectAction(ctx context.Context, recorder *recording.R
 	p.setStatus(Idle)
 }
 
+// archiveSession saves the session audio and outcome when archiving is enabled
+func (p *pipeline) archiveSession(session *archive.Session) {
+	if p.archive == nil {
+		return
+	}
+
+	p.audioMu.Lock()
+	session.Audio = p.audio
+	p.audio = nil
+	p.audioMu.Unlock()
+
+	transCfg := p.config.ToTranscriberConfig()
+	session.SampleRate = transCfg.SampleRate
+	session.Channels = transCfg.Channels
+	session.Format = "s16"
+	session.Language = transCfg.Language
+	if session.Provider == "" {
+		session.Provider = transCfg.Provider
+	}
+	// The model is only known when a single provider produced the transcript
+	for _, provider := range append([]transcriber.Config{transCfg}, transCfg.Fallbacks...) {
+		if provider.Provider == session.Provider {
+			session.Model = provider.Model
+			break
+		}
+	}
+
+	path, err := p.archive.Save(*session)
+	if err != nil {
+		log.Printf("Pipeline: Failed to archive session: %v", err)
+		return
+	}
+	log.Printf("Pipeline: Session archived to %s", path)
+}
+
+// handlePauseAction stops keeping audio until the recording is resumed
+func (p *pipeline) handlePauseAction() {
+	if p.Status() != Transcribing {
+		log.Printf("Pipeline: Pause action received, but not recording, ignoring")
+		return
+	}
+
+	log.Printf("Pipeline: Pausing recording, audio is discarded until resumed")
+	p.paused.Store(true)
+	p.setLevel(recording.Level{})
+	p.setStatus(Paused)
+}
+
+func (p *pipeline) handleResumeAction() {
+	if p.Status() != Paused {
+		log.Printf("Pipeline: Resume action received, but not paused, ignoring")
+		return
+	}
+
+	log.Printf("Pipeline: Resuming recording")
+	p.paused.Store(false)
+	p.setStatus(Transcribing)
+}
+
 func (p *pipeline) Stop() {
 	p.stopOnce.Do(func() {
 		cancel := p.getCancel()
@@ -2,10 +2,14 @@ package pipeline
 
 import (
 	"context"
+	"fmt"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/leonardotrapani/hyprvoice/internal/config"
+	"github.com/leonardotrapani/hyprvoice/internal/recording"
+	"github.com/leonardotrapani/hyprvoice/internal/transcriber"
 )
 
 func TestNew(t *testing.T) {
@@ -278,6 +282,7 @@ func TestStatus_String(t *testing.T) {
 		{Recording, "recording"},
 		{Transcribing, "transcribing"},
 		{Injecting, "injecting"},
+		{Paused, "paused"},
 	}
 
 	for _, tt := range tests {
@@ -295,6 +300,8 @@ func TestAction_String(t *testing.T) {
 		expected string
 	}{
 		{Inject, "inject"},
+		{Pause, "pause"},
+		{Resume, "resume"},
 	}
 
 	for _, tt := range tests {
@@ -306,6 +313,104 @@ func TestAction_String(t *testing.T) {
 	}
 }
 
+func TestPipeline_PauseResume(t *testing.T) {
+	cfg := &config.Config{
+		Recording: config.RecordingConfig{
+			SampleRate:        16000,
+			Channels:          1,
+			Format:            "s16",
+			ChannelBufferSize: 10,
+		},
+	}
+	p := New(cfg).(*pipeline)
+
+	// Pausing is only possible while capturing
+	p.handlePauseAction()
+	if p.Status() == Paused {
+		t.Fatalf("pause from idle should be ignored")
+	}
+
+	p.setStatus(Transcribing)
+	frameCh := make(chan recording.AudioFrame, 3)
+	out := p.forwardFrames(context.Background(), frameCh)
+
+	frameCh <- recording.AudioFrame{Data: []byte{1, 0}}
+	if frame := <-out; frame.Data[0] != 1 {
+		t.Errorf("frame before pause = %v, want first frame", frame.Data)
+	}
+
+	p.handlePauseAction()
+	if p.Status() != Paused {
+		t.Fatalf("Status() = %s, want %s", p.Status(), Paused)
+	}
+	frameCh <- recording.AudioFrame{Data: []byte{2, 0}}
+
+	// Give the discarded frame time to pass through before resuming
+	time.Sleep(50 * time.Millisecond)
+	p.handleResumeAction()
+	if p.Status() != Transcribing {
+		t.Fatalf("Status() = %s, want %s", p.Status(), Transcribing)
+	}
+
+	frameCh <- recording.AudioFrame{Data: []byte{3, 0}}
+	close(frameCh)
+
+	var got []byte
+	for frame := range out {
+		got = append(got, frame.Data[0])
+	}
+	if len(got) != 1 || got[0] != 3 {
+		t.Errorf("frames after resume = %v, want only the frame captured after resuming", got)
+	}
+}
+
+func TestPipeline_TimeoutTimers(t *testing.T) {
+	tests := []struct {
+		name        string
+		warning     time.Duration
+		action      string
+		wantWarning bool
+		wantLimit   bool
+	}{
+		{"warning then transcribe", 40 * time.Millisecond, "transcribe", true, true},
+		{"no warning", 0, "transcribe", false, true},
+		{"abort leaves the limit to the context", 40 * time.Millisecond, "abort", true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				Recording: config.RecordingConfig{
+					Timeout:        60 * time.Millisecond,
+					TimeoutWarning: tt.warning,
+					TimeoutAction:  tt.action,
+				},
+			}
+			p := New(cfg).(*pipeline)
+
+			start := time.Now()
+			warningC, limitC, stop := p.timeoutTimers()
+			defer stop()
+
+			if (warningC != nil) != tt.wantWarning || (limitC != nil) != tt.wantLimit {
+				t.Fatalf("timeoutTimers() warning=%v limit=%v, want %v and %v", warningC != nil, limitC != nil, tt.wantWarning, tt.wantLimit)
+			}
+			if warningC != nil {
+				<-warningC
+				if elapsed := time.Since(start); elapsed < 20*time.Millisecond || elapsed >= 60*time.Millisecond {
+					t.Errorf("warning fired after %v, want 20ms before the 60ms timeout", elapsed)
+				}
+			}
+			if limitC != nil {
+				<-limitC
+				if elapsed := time.Since(start); elapsed < 60*time.Millisecond {
+					t.Errorf("limit fired after %v, want 60ms", elapsed)
+				}
+			}
+		})
+	}
+}
+
 func TestPipelineError_Struct(t *testing.T) {
 	err := PipelineError{
 		Title:   "Test Title",
@@ -377,3 +482,104 @@ func TestPipeline_ConcurrentAccess(t *testing.T) {
 	<-done
 	<-done
 }
+
+// recordingInjector records injected text, failing for texts in fail
+type recordingInjector struct {
+	mu      sync.Mutex
+	texts   []string
+	fail    map[string]bool
+	release chan struct{} // when set, each injection waits for it
+}
+
+func (r *recordingInjector) Inject(ctx context.Context, text string) error {
+	if r.release != nil {
+		<-r.release
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.fail[text] {
+		return fmt.Errorf("typing failed")
+	}
+	r.texts = append(r.texts, text)
+	return nil
+}
+
+func TestLiveTyper(t *testing.T) {
+	t.Run("types segments with spacing and leaves the rest", func(t *testing.T) {
+		injector := &recordingInjector{}
+		live := startLiveTyper(context.Background(), injector)
+		live.add("Hello there.")
+		live.add("How are you?")
+		live.finish()
+
+		if want := "[Hello there.  How are you?]"; fmt.Sprint(injector.texts) != want {
+			t.Errorf("injected %q, want %s", injector.texts, want)
+		}
+		if got := live.remainder("Hello there. How are you? Fine, thanks."); got != " Fine, thanks." {
+			t.Errorf("remainder() = %q, want %q", got, " Fine, thanks.")
+		}
+		if got := live.remainder("Hello there. How are you?"); got != "" {
+			t.Errorf("remainder() = %q, want nothing", got)
+		}
+	})
+
+	t.Run("stops typing live after a failure", func(t *testing.T) {
+		injector := &recordingInjector{fail: map[string]bool{" two": true}}
+		live := startLiveTyper(context.Background(), injector)
+		live.add("one")
+		live.add("two")
+		live.add("three")
+		live.finish()
+
+		if want := "[one]"; fmt.Sprint(injector.texts) != want {
+			t.Errorf("injected %q, want %s", injector.texts, want)
+		}
+		if got := live.remainder("one two three"); got != " two three" {
+			t.Errorf("remainder() = %q, want %q", got, " two three")
+		}
+	})
+
+	t.Run("cancelling keeps only what was typed", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		injector := &recordingInjector{release: make(chan struct{})}
+		live := startLiveTyper(ctx, injector)
+		live.add("typing")
+
+		// The segment being typed is finished, the queued one is dropped
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+		live.add("queued")
+		close(injector.release)
+		live.finish()
+
+		if want := "[typing]"; fmt.Sprint(injector.texts) != want {
+			t.Errorf("injected %q, want %s", injector.texts, want)
+		}
+	})
+}
+
+func TestTranscriptionErrorMessage(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"auth", &transcriber.HTTPError{StatusCode: 401}, "Provider rejected the API key, check transcription.api_key"},
+		{"rate limit", &transcriber.HTTPError{StatusCode: 429}, "Provider rate limit reached, try again shortly"},
+		{"server", fmt.Errorf("openai transcription: %w", &transcriber.HTTPError{StatusCode: 502}), "Provider is having problems, try again later"},
+		{"network", context.DeadlineExceeded, "Could not reach the transcription provider"},
+		{"bad request", &transcriber.HTTPError{StatusCode: 400}, "Provider rejected the request, check the model and language"},
+		{"unknown", fmt.Errorf("whisper-cli: exit status 1"), "Failed to retrieve transcription"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := transcriptionErrorMessage(tt.err, "Failed to retrieve transcription"); got != tt.want {
+				t.Errorf("transcriptionErrorMessage() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package recording
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+)
+
+type arecordSource struct{}
+
+func NewArecordSource() Source {
+	return &arecordSource{}
+}
+
+func (a *arecordSource) Name() string {
+	return "arecord"
+}
+
+func (a *arecordSource) Available(ctx context.Context) error {
+	if _, err := exec.LookPath("arecord"); err != nil {
+		return fmt.Errorf("arecord not found: %w (install alsa-utils)", err)
+	}
+	return nil
+}
+
+func (a *arecordSource) Command(ctx context.Context, config Config) *exec.Cmd {
+	return exec.CommandContext(ctx, "arecord", arecordArgs(config)...)
+}
+
+func arecordArgs(config Config) []string {
+	args := []string{
+		"-q",
+		"-t", "raw",
+		"-f", arecordFormat(config.Format),
+		"-r", strconv.Itoa(config.SampleRate),
+		"-c", strconv.Itoa(config.Channels),
+	}
+	if config.Device != "" {
+		args = append(args, "-D", config.Device)
+	}
+	return append(args, "-") // stdout
+}
+
+func arecordFormat(format string) string {
+	switch format {
+	case "s24":
+		return "S24_3LE"
+	case "s32":
+		return "S32_LE"
+	case "f32":
+		return "FLOAT_LE"
+	default:
+		return "S16_LE"
+	}
+}
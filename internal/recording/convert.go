@@ -0,0 +1,248 @@
+package recording
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// resampleTaps is the number of zero crossings of the sinc kernel on each side
+const resampleTaps = 16
+
+// resamplePhases is how finely the position between two input samples is
+// quantized, so kernels can be computed once per phase and reused
+const resamplePhases = 512
+
+// Converter turns captured audio in any supported format, rate and channel
+// count into s16 little-endian audio at the target rate and channel count.
+// It is streaming: state is kept between calls, so a Converter must be used
+// for one stream only.
+type Converter struct {
+	format      string
+	channels    int
+	inRate      int
+	outRate     int
+	outChannels int
+
+	pending []byte // partial sample frame left over from the previous call
+
+	// resampler state, per output channel
+	history [][]float64
+	pos     float64           // position of the next output sample in history
+	step    float64           // input samples per output sample
+	cutoff  float64           // low-pass cutoff relative to the input Nyquist frequency
+	kernels map[int][]float64 // kernel taps by phase
+}
+
+func NewConverter(config Config, outRate, outChannels int) *Converter {
+	c := &Converter{
+		format:      config.Format,
+		channels:    config.Channels,
+		inRate:      config.SampleRate,
+		outRate:     outRate,
+		outChannels: outChannels,
+	}
+	if c.resampling() {
+		c.step = float64(c.inRate) / float64(c.outRate)
+		c.cutoff = min(1, float64(c.outRate)/float64(c.inRate))
+		c.history = make([][]float64, outChannels)
+		for ch := range c.history {
+			// Leading silence centers the kernel on the first real sample
+			c.history[ch] = make([]float64, c.halfWidth())
+		}
+		c.pos = float64(c.halfWidth())
+		c.kernels = make(map[int][]float64)
+	}
+	return c
+}
+
+// Passthrough reports whether the input already has the target layout
+func (c *Converter) Passthrough() bool {
+	return c.format == "s16" && c.channels == c.outChannels && !c.resampling()
+}
+
+func (c *Converter) resampling() bool {
+	return c.inRate != c.outRate
+}
+
+// Convert returns the converted audio for data
+func (c *Converter) Convert(data []byte) []byte {
+	if c.Passthrough() {
+		return data
+	}
+
+	frameSize := SampleSize(c.format) * c.channels
+	if len(c.pending) > 0 {
+		data = append(c.pending, data...)
+		c.pending = nil
+	}
+	if rem := len(data) % frameSize; rem != 0 {
+		c.pending = append([]byte(nil), data[len(data)-rem:]...)
+		data = data[:len(data)-rem]
+	}
+
+	channels := c.mix(c.decode(data), len(data)/frameSize)
+	if c.resampling() {
+		channels = c.resample(channels, false)
+	}
+	return encodeS16(channels)
+}
+
+// Flush returns the audio still held by the resampler at the end of the stream
+func (c *Converter) Flush() []byte {
+	if !c.resampling() {
+		return nil
+	}
+	return encodeS16(c.resample(make([][]float64, c.outChannels), true))
+}
+
+// decode converts interleaved samples to floats in [-1, 1]
+func (c *Converter) decode(data []byte) []float64 {
+	size := SampleSize(c.format)
+	samples := make([]float64, len(data)/size)
+	for i := range samples {
+		b := data[i*size:]
+		switch c.format {
+		case "s24":
+			v := int32(b[0]) | int32(b[1])<<8 | int32(int8(b[2]))<<16
+			samples[i] = float64(v) / (1 << 23)
+		case "s32":
+			samples[i] = float64(int32(binary.LittleEndian.Uint32(b))) / (1 << 31)
+		case "f32":
+			samples[i] = float64(math.Float32frombits(binary.LittleEndian.Uint32(b)))
+		default:
+			samples[i] = float64(int16(binary.LittleEndian.Uint16(b))) / (1 << 15)
+		}
+	}
+	return samples
+}
+
+// mix splits interleaved samples into the output channels, averaging all
+// input channels when downmixing and duplicating them when upmixing
+func (c *Converter) mix(samples []float64, frames int) [][]float64 {
+	out := make([][]float64, c.outChannels)
+	for ch := range out {
+		out[ch] = make([]float64, frames)
+	}
+
+	for f := 0; f < frames; f++ {
+		frame := samples[f*c.channels : (f+1)*c.channels]
+		if c.outChannels == c.channels {
+			for ch := range out {
+				out[ch][f] = frame[ch]
+			}
+			continue
+		}
+
+		var sum float64
+		for _, v := range frame {
+			sum += v
+		}
+		for ch := range out {
+			out[ch][f] = sum / float64(c.channels)
+		}
+	}
+	return out
+}
+
+// resample runs a windowed-sinc interpolator over the new samples. The
+// kernel is widened when downsampling so it also acts as the anti-aliasing
+// filter.
+func (c *Converter) resample(input [][]float64, flush bool) [][]float64 {
+	halfWidth := c.halfWidth()
+	for ch := range c.history {
+		c.history[ch] = append(c.history[ch], input[ch]...)
+		if flush {
+			c.history[ch] = append(c.history[ch], make([]float64, halfWidth)...)
+		}
+	}
+
+	available := len(c.history[0])
+	out := make([][]float64, len(c.history))
+	for c.pos+float64(halfWidth) < float64(available) {
+		center := int(c.pos)
+		frac := c.pos - float64(center)
+		weights := c.weights(frac)
+		for ch, hist := range c.history {
+			var sum, weight float64
+			for j, w := range weights {
+				i := center - halfWidth + 1 + j
+				if i < 0 || i >= available {
+					continue
+				}
+				sum += hist[i] * w
+				weight += w
+			}
+			if weight != 0 {
+				sum /= weight
+			}
+			out[ch] = append(out[ch], sum)
+		}
+		c.pos += c.step
+	}
+
+	// Drop history the kernel will not reach again
+	if drop := int(c.pos) - halfWidth; drop > 0 {
+		drop = min(drop, available)
+		for ch := range c.history {
+			c.history[ch] = append(c.history[ch][:0], c.history[ch][drop:]...)
+		}
+		c.pos -= float64(drop)
+	}
+	return out
+}
+
+// weights returns the kernel taps for an output sample frac samples after
+// an input sample
+func (c *Converter) weights(frac float64) []float64 {
+	phase := int(math.Round(frac * resamplePhases))
+	if w, ok := c.kernels[phase]; ok {
+		return w
+	}
+
+	halfWidth := c.halfWidth()
+	w := make([]float64, 2*halfWidth)
+	for j := range w {
+		w[j] = c.kernel(float64(j-halfWidth+1) - float64(phase)/resamplePhases)
+	}
+	c.kernels[phase] = w
+	return w
+}
+
+// halfWidth is the number of input samples the kernel spans on each side
+func (c *Converter) halfWidth() int {
+	return int(math.Ceil(resampleTaps / c.cutoff))
+}
+
+// kernel is a Blackman-windowed sinc low-pass at the converter cutoff
+func (c *Converter) kernel(x float64) float64 {
+	halfWidth := float64(c.halfWidth())
+	if math.Abs(x) >= halfWidth {
+		return 0
+	}
+
+	t := x * c.cutoff
+	sinc := 1.0
+	if t != 0 {
+		sinc = math.Sin(math.Pi*t) / (math.Pi * t)
+	}
+	n := (x + halfWidth) / (2 * halfWidth)
+	window := 0.42 - 0.5*math.Cos(2*math.Pi*n) + 0.08*math.Cos(4*math.Pi*n)
+	return sinc * window
+}
+
+// encodeS16 interleaves the channels as s16 little-endian samples
+func encodeS16(channels [][]float64) []byte {
+	if len(channels) == 0 {
+		return nil
+	}
+
+	frames := len(channels[0])
+	out := make([]byte, 0, frames*len(channels)*2)
+	for f := 0; f < frames; f++ {
+		for _, ch := range channels {
+			v := max(-1, min(1, ch[f]))
+			out = binary.LittleEndian.AppendUint16(out, uint16(int16(math.Round(v*32767))))
+		}
+	}
+	return out
+}
@@ -0,0 +1,118 @@
+package recording
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Device is a PipeWire capture node usable as recording.device
+type Device struct {
+	ID          int
+	Name        string // node.name, passed to pw-record --target
+	Description string
+	Default     bool
+}
+
+// pwDumpObject is the subset of a pw-dump entry needed to enumerate capture nodes
+type pwDumpObject struct {
+	ID   int    `json:"id"`
+	Type string `json:"type"`
+	Info struct {
+		Props map[string]any `json:"props"`
+	} `json:"info"`
+	Props    map[string]any `json:"props"`
+	Metadata []struct {
+		Key   string          `json:"key"`
+		Value json.RawMessage `json:"value"`
+	} `json:"metadata"`
+}
+
+// ListDevices returns the PipeWire capture devices reported by pw-dump
+func ListDevices(ctx context.Context) ([]Device, error) {
+	if _, err := exec.LookPath("pw-dump"); err != nil {
+		return nil, fmt.Errorf("pw-dump not found: %w (install pipewire)", err)
+	}
+
+	dumpCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	out, err := exec.CommandContext(dumpCtx, "pw-dump").Output()
+	if err != nil {
+		return nil, fmt.Errorf("run pw-dump: %w", err)
+	}
+
+	return parsePwDump(out)
+}
+
+func parsePwDump(data []byte) ([]Device, error) {
+	var objects []pwDumpObject
+	if err := json.Unmarshal(data, &objects); err != nil {
+		return nil, fmt.Errorf("parse pw-dump output: %w", err)
+	}
+
+	defaultSource := ""
+	for _, obj := range objects {
+		if obj.Type != "PipeWire:Interface:Metadata" || propString(obj.Props, "metadata.name") != "default" {
+			continue
+		}
+		for _, entry := range obj.Metadata {
+			if entry.Key != "default.audio.source" {
+				continue
+			}
+			var value struct {
+				Name string `json:"name"`
+			}
+			if err := json.Unmarshal(entry.Value, &value); err == nil {
+				defaultSource = value.Name
+			}
+		}
+	}
+
+	var devices []Device
+	for _, obj := range objects {
+		if obj.Type != "PipeWire:Interface:Node" {
+			continue
+		}
+		props := obj.Info.Props
+		if !strings.HasPrefix(propString(props, "media.class"), "Audio/Source") {
+			continue
+		}
+
+		name := propString(props, "node.name")
+		description := propString(props, "node.description")
+		if description == "" {
+			description = propString(props, "node.nick")
+		}
+
+		devices = append(devices, Device{
+			ID:          obj.ID,
+			Name:        name,
+			Description: description,
+			Default:     name != "" && name == defaultSource,
+		})
+	}
+
+	return devices, nil
+}
+
+// FindDevice looks up a device by node name or numeric id, the forms pw-record --target accepts
+func FindDevice(devices []Device, target string) (Device, bool) {
+	for _, d := range devices {
+		if d.Name == target || strconv.Itoa(d.ID) == target {
+			return d, true
+		}
+	}
+	return Device{}, false
+}
+
+func propString(props map[string]any, key string) string {
+	if v, ok := props[key].(string); ok {
+		return v
+	}
+	return ""
+}
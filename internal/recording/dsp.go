@@ -0,0 +1,224 @@
+package recording
+
+import (
+	"encoding/binary"
+	"math"
+	"time"
+)
+
+// DSPConfig controls the preprocessing applied to captured audio before it is transcribed
+type DSPConfig struct {
+	DCRemoval          bool
+	HighPassCutoff     float64 // Hz, 0 disables the high-pass filter
+	Normalize          bool
+	NormalizeTarget    float64 // RMS level (0.0-1.0) speech is brought to
+	NormalizeMaxGain   float64 // Largest linear gain applied to quiet input
+	NoiseGate          bool
+	NoiseGateThreshold float64 // RMS level (0.0-1.0) below which audio is muted
+}
+
+// Enabled reports whether any processing stage is turned on
+func (c DSPConfig) Enabled() bool {
+	return c.DCRemoval || c.HighPassCutoff > 0 || c.Normalize || c.NoiseGate
+}
+
+const (
+	dcPole          = 0.995 // pole of the DC blocker, around 12 Hz at 16 kHz
+	gateHold        = 200 * time.Millisecond
+	gateAttack      = 5 * time.Millisecond
+	gateRelease     = 50 * time.Millisecond
+	gainAttack      = 10 * time.Millisecond  // how fast gain drops when input gets loud
+	gainRelease     = 500 * time.Millisecond // how fast gain recovers when input gets quiet
+	normalizeFloor  = 0.003                  // frames quieter than this do not move the gain
+	samplePeakLimit = 0.99
+	butterworthQ    = 1 / math.Sqrt2
+)
+
+// DSP processes a stream of s16 little-endian frames. It keeps filter state
+// between frames, so a single DSP must be used for one stream only.
+type DSP struct {
+	config     DSPConfig
+	sampleRate int
+	channels   int
+
+	dcIn, dcOut []float64 // per channel DC blocker state
+	highPass    []biquad  // per channel high-pass filter
+
+	gate     float64 // current gate gain, 0 (closed) to 1 (open)
+	gateHeld time.Duration
+	gain     float64 // current normalization gain
+}
+
+func NewDSP(config DSPConfig, sampleRate, channels int) *DSP {
+	d := &DSP{
+		config:     config,
+		sampleRate: sampleRate,
+		channels:   channels,
+		dcIn:       make([]float64, channels),
+		dcOut:      make([]float64, channels),
+		gate:       1,
+		gain:       1,
+	}
+	if config.HighPassCutoff > 0 {
+		d.highPass = make([]biquad, channels)
+		for i := range d.highPass {
+			d.highPass[i] = newHighPass(config.HighPassCutoff, float64(sampleRate))
+		}
+	}
+	return d
+}
+
+// Process runs the enabled stages over data in place and returns it
+func (d *DSP) Process(data []byte) []byte {
+	if d.channels <= 0 || d.sampleRate <= 0 {
+		return data
+	}
+
+	samples := make([]float64, len(data)/2)
+	for i := range samples {
+		samples[i] = float64(int16(binary.LittleEndian.Uint16(data[i*2:]))) / 32768
+	}
+
+	for i, x := range samples {
+		ch := i % d.channels
+		if d.config.DCRemoval {
+			y := x - d.dcIn[ch] + dcPole*d.dcOut[ch]
+			d.dcIn[ch], d.dcOut[ch] = x, y
+			x = y
+		}
+		if d.highPass != nil {
+			x = d.highPass[ch].process(x)
+		}
+		samples[i] = x
+	}
+
+	if d.config.NoiseGate {
+		d.applyGate(samples)
+	}
+	if d.config.Normalize {
+		d.applyGain(samples)
+	}
+
+	for i, x := range samples {
+		x = max(-samplePeakLimit, min(samplePeakLimit, x))
+		binary.LittleEndian.PutUint16(data[i*2:], uint16(int16(math.Round(x*32767))))
+	}
+	return data
+}
+
+// applyGate mutes frames quieter than the threshold once the hold time has
+// passed, ramping the gain to avoid clicks
+func (d *DSP) applyGate(samples []float64) {
+	duration := d.duration(len(samples))
+	target := 1.0
+	if rms(samples) < d.config.NoiseGateThreshold {
+		d.gateHeld += duration
+		if d.gateHeld >= gateHold {
+			target = 0
+		}
+	} else {
+		d.gateHeld = 0
+	}
+
+	ramp := gateAttack
+	if target < d.gate {
+		ramp = gateRelease
+	}
+	step := d.rampStep(ramp)
+	for i := range samples {
+		if i%d.channels == 0 {
+			d.gate = approach(d.gate, target, step)
+		}
+		samples[i] *= d.gate
+	}
+}
+
+// applyGain moves the gain towards the level that brings the frame to the
+// normalization target, never exceeding the configured maximum
+func (d *DSP) applyGain(samples []float64) {
+	level := rms(samples)
+	target := d.gain
+	if level >= normalizeFloor {
+		target = max(1, min(d.config.NormalizeMaxGain, d.config.NormalizeTarget/level))
+		// Never push the frame's peak into clipping
+		if peak := peakOf(samples); peak > 0 {
+			target = min(target, max(1, samplePeakLimit/peak))
+		}
+	}
+
+	ramp := gainRelease
+	if target < d.gain {
+		ramp = gainAttack
+	}
+	step := d.rampStep(ramp) * max(1, d.config.NormalizeMaxGain)
+	for i := range samples {
+		if i%d.channels == 0 {
+			d.gain = approach(d.gain, target, step)
+		}
+		samples[i] *= d.gain
+	}
+}
+
+func (d *DSP) duration(samples int) time.Duration {
+	return time.Duration(samples/d.channels) * time.Second / time.Duration(d.sampleRate)
+}
+
+// rampStep is the per-sample change that covers a unit range in ramp
+func (d *DSP) rampStep(ramp time.Duration) float64 {
+	return 1 / (ramp.Seconds() * float64(d.sampleRate))
+}
+
+func approach(current, target, step float64) float64 {
+	if current < target {
+		return min(current+step, target)
+	}
+	return max(current-step, target)
+}
+
+func rms(samples []float64) float64 {
+	if len(samples) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, x := range samples {
+		sum += x * x
+	}
+	return math.Sqrt(sum / float64(len(samples)))
+}
+
+func peakOf(samples []float64) float64 {
+	var peak float64
+	for _, x := range samples {
+		peak = max(peak, math.Abs(x))
+	}
+	return peak
+}
+
+// biquad is a second order IIR filter in direct form I
+type biquad struct {
+	b0, b1, b2, a1, a2 float64
+	x1, x2, y1, y2     float64
+}
+
+// newHighPass returns a Butterworth high-pass filter (RBJ audio EQ cookbook)
+func newHighPass(cutoff, sampleRate float64) biquad {
+	w0 := 2 * math.Pi * cutoff / sampleRate
+	alpha := math.Sin(w0) / (2 * butterworthQ)
+	cosW0 := math.Cos(w0)
+	a0 := 1 + alpha
+
+	return biquad{
+		b0: (1 + cosW0) / 2 / a0,
+		b1: -(1 + cosW0) / a0,
+		b2: (1 + cosW0) / 2 / a0,
+		a1: -2 * cosW0 / a0,
+		a2: (1 - alpha) / a0,
+	}
+}
+
+func (f *biquad) process(x float64) float64 {
+	y := f.b0*x + f.b1*f.x1 + f.b2*f.x2 - f.a1*f.y1 - f.a2*f.y2
+	f.x2, f.x1 = f.x1, x
+	f.y2, f.y1 = f.y1, y
+	return y
+}
@@ -0,0 +1,77 @@
+package recording
+
+import (
+	"log"
+	"time"
+)
+
+// failoverDelay gives the sound server a moment to settle after a device
+// disappears before capture is restarted on the next one
+const failoverDelay = 250 * time.Millisecond
+
+// deviceList walks the capture devices in priority order: the configured
+// device, the fallback devices, then the default source
+type deviceList struct {
+	devices []string
+	pos     int
+}
+
+func newDeviceList(config Config) *deviceList {
+	candidates := append([]string{config.Device}, config.FallbackDevices...)
+	candidates = append(candidates, "")
+
+	seen := make(map[string]bool)
+	l := &deviceList{}
+	for _, device := range candidates {
+		if !seen[device] {
+			seen[device] = true
+			l.devices = append(l.devices, device)
+		}
+	}
+	return l
+}
+
+func (l *deviceList) current() string {
+	return l.devices[l.pos]
+}
+
+// next moves to the following device, reporting false once none is left
+func (l *deviceList) next() (string, bool) {
+	if l.pos+1 >= len(l.devices) {
+		return "", false
+	}
+	l.pos++
+	return l.devices[l.pos], true
+}
+
+// rewind goes back to the preferred device
+func (l *deviceList) rewind() {
+	l.pos = 0
+}
+
+// OnDeviceChange registers fn to be called when capture switches to another
+// device because the previous one disappeared
+func (r *Recorder) OnDeviceChange(fn func(from, to string)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onDeviceChange = fn
+}
+
+func (r *Recorder) deviceChanged(from, to string) {
+	r.mu.Lock()
+	fn := r.onDeviceChange
+	r.mu.Unlock()
+
+	log.Printf("Recording: now capturing from %s", deviceName(to))
+	if fn != nil {
+		fn(from, to)
+	}
+}
+
+// deviceName describes a device for logs and messages
+func deviceName(device string) string {
+	if device == "" {
+		return "the default source"
+	}
+	return device
+}
@@ -0,0 +1,70 @@
+package recording
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+type ffmpegSource struct{}
+
+func NewFfmpegSource() Source {
+	return &ffmpegSource{}
+}
+
+func (f *ffmpegSource) Name() string {
+	return "ffmpeg"
+}
+
+func (f *ffmpegSource) Available(ctx context.Context) error {
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		return fmt.Errorf("ffmpeg not found: %w (install ffmpeg)", err)
+	}
+	return nil
+}
+
+func (f *ffmpegSource) Command(ctx context.Context, config Config) *exec.Cmd {
+	return exec.CommandContext(ctx, "ffmpeg", ffmpegArgs(config)...)
+}
+
+// ffmpegArgs captures through PulseAudio (also served by pipewire-pulse), or
+// straight from ALSA when the device is an ALSA hardware name. Sink monitors
+// are always read through PulseAudio.
+func ffmpegArgs(config Config) []string {
+	inputFormat, device := "pulse", config.Device
+	if strings.HasPrefix(device, "hw:") || strings.HasPrefix(device, "plughw:") {
+		inputFormat = "alsa"
+	}
+	if config.Input == InputMonitor {
+		inputFormat, device = "pulse", pulseMonitorSource(device)
+	}
+	if device == "" {
+		device = "default"
+	}
+
+	return []string{
+		"-hide_banner",
+		"-loglevel", "error",
+		"-f", inputFormat,
+		"-i", device,
+		"-ac", strconv.Itoa(config.Channels),
+		"-ar", strconv.Itoa(config.SampleRate),
+		"-f", ffmpegFormat(config.Format),
+		"-", // stdout
+	}
+}
+
+func ffmpegFormat(format string) string {
+	switch format {
+	case "s24":
+		return "s24le"
+	case "s32":
+		return "s32le"
+	case "f32":
+		return "f32le"
+	default:
+		return "s16le"
+	}
+}
@@ -0,0 +1,304 @@
+package recording
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"math"
+)
+
+const (
+	flacBlockSize         = 4096
+	flacMaxPartitionOrder = 8
+	flacMaxFixedOrder     = 4
+	flacMaxRiceParam      = 14 // 15 is the escape code of the 4-bit parameter method
+)
+
+// EncodeFLAC compresses s16 little-endian audio into a FLAC stream. It uses
+// the fixed predictors and Rice coded residuals, which is enough to roughly
+// halve speech recordings while staying simple and fast.
+func EncodeFLAC(pcm []byte, sampleRate, channels int) []byte {
+	frames := len(pcm) / (2 * channels)
+	pcm = pcm[:frames*2*channels]
+
+	w := &bitWriter{}
+	w.buf = append(w.buf, "fLaC"...)
+
+	// STREAMINFO, the only metadata block
+	w.writeBits(1, 1) // last metadata block
+	w.writeBits(0, 7)
+	w.writeBits(34, 24)
+	w.writeBits(flacBlockSize, 16)
+	w.writeBits(flacBlockSize, 16)
+	w.writeBits(0, 24) // frame sizes unknown
+	w.writeBits(0, 24)
+	w.writeBits(uint64(sampleRate), 20)
+	w.writeBits(uint64(channels-1), 3)
+	w.writeBits(15, 5) // 16 bits per sample
+	w.writeBits(uint64(frames)>>32, 4)
+	w.writeBits(uint64(frames)&0xFFFFFFFF, 32)
+	sum := md5.Sum(pcm) // the MD5 covers the samples as little-endian interleaved bytes
+	w.buf = append(w.buf, sum[:]...)
+
+	samples := make([][]int64, channels)
+	for frame := 0; frame*flacBlockSize < frames; frame++ {
+		start := frame * flacBlockSize
+		n := min(flacBlockSize, frames-start)
+		for ch := range samples {
+			samples[ch] = samples[ch][:0]
+			for i := start; i < start+n; i++ {
+				v := int16(binary.LittleEndian.Uint16(pcm[(i*channels+ch)*2:]))
+				samples[ch] = append(samples[ch], int64(v))
+			}
+		}
+		w.buf = appendFLACFrame(w.buf, uint64(frame), samples)
+	}
+
+	return w.buf
+}
+
+func appendFLACFrame(buf []byte, number uint64, samples [][]int64) []byte {
+	n := len(samples[0])
+	w := &bitWriter{}
+
+	w.writeBits(0xFFF8, 16) // sync code, fixed block size stream
+	if n == flacBlockSize {
+		w.writeBits(0xC, 4) // 256 * 2^(12-8)
+	} else {
+		w.writeBits(0x7, 4) // 16-bit block size follows the frame number
+	}
+	w.writeBits(0, 4) // sample rate from STREAMINFO
+	w.writeBits(uint64(len(samples)-1), 4)
+	w.writeBits(0x4, 3) // 16 bits per sample
+	w.writeBits(0, 1)
+	w.buf = appendUTF8Number(w.buf, number)
+	if n != flacBlockSize {
+		w.writeBits(uint64(n-1), 16)
+	}
+	w.buf = append(w.buf, crc8(w.buf))
+
+	for _, ch := range samples {
+		writeSubframe(w, ch)
+	}
+	w.align()
+
+	crc := crc16(w.buf)
+	w.buf = append(w.buf, byte(crc>>8), byte(crc))
+	return append(buf, w.buf...)
+}
+
+// writeSubframe picks the cheapest of a constant, verbatim or fixed
+// prediction subframe for one channel of a block
+func writeSubframe(w *bitWriter, samples []int64) {
+	constant := true
+	for _, v := range samples[1:] {
+		if v != samples[0] {
+			constant = false
+			break
+		}
+	}
+	if constant {
+		w.writeBits(0, 8)
+		w.writeBits(uint64(samples[0])&0xFFFF, 16)
+		return
+	}
+
+	bestOrder, bestBits := -1, 16*len(samples)
+	for order := 0; order <= flacMaxFixedOrder && order < len(samples); order++ {
+		_, _, bits := riceParams(fixedResiduals(samples, order), order, len(samples))
+		bits += order * 16
+		if bits < bestBits {
+			bestOrder, bestBits = order, bits
+		}
+	}
+
+	if bestOrder < 0 {
+		w.writeBits(0x02, 8) // verbatim
+		for _, v := range samples {
+			w.writeBits(uint64(v)&0xFFFF, 16)
+		}
+		return
+	}
+
+	w.writeBits(uint64(0x08|bestOrder)<<1, 8) // fixed, no wasted bits
+	for _, v := range samples[:bestOrder] {
+		w.writeBits(uint64(v)&0xFFFF, 16)
+	}
+
+	residuals := fixedResiduals(samples, bestOrder)
+	partitionOrder, params, _ := riceParams(residuals, bestOrder, len(samples))
+	w.writeBits(0, 2) // Rice coding with 4-bit parameters
+	w.writeBits(uint64(partitionOrder), 4)
+	pos := 0
+	for i, k := range params {
+		count := len(samples) >> partitionOrder
+		if i == 0 {
+			count -= bestOrder
+		}
+		w.writeBits(uint64(k), 4)
+		for _, r := range residuals[pos : pos+count] {
+			w.writeRice(zigzag(r), k)
+		}
+		pos += count
+	}
+}
+
+// fixedResiduals returns the prediction error of the fixed polynomial
+// predictor of the given order for every sample after the warm-up samples
+func fixedResiduals(s []int64, order int) []int64 {
+	out := make([]int64, 0, len(s)-order)
+	for i := order; i < len(s); i++ {
+		var r int64
+		switch order {
+		case 0:
+			r = s[i]
+		case 1:
+			r = s[i] - s[i-1]
+		case 2:
+			r = s[i] - 2*s[i-1] + s[i-2]
+		case 3:
+			r = s[i] - 3*s[i-1] + 3*s[i-2] - s[i-3]
+		case 4:
+			r = s[i] - 4*s[i-1] + 6*s[i-2] - 4*s[i-3] + s[i-4]
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+// riceParams chooses the partition order and per-partition Rice parameters
+// that minimize the estimated size of the residual, returned in bits
+func riceParams(residuals []int64, order, blockSize int) (int, []int, int) {
+	bestOrder, bestBits := 0, math.MaxInt
+	var bestParams []int
+	for p := 0; p <= flacMaxPartitionOrder; p++ {
+		if blockSize%(1<<p) != 0 || blockSize>>p <= order {
+			break
+		}
+		size := blockSize >> p
+		params := make([]int, 1<<p)
+		bits := 6
+		pos := 0
+		for i := range params {
+			count := size
+			if i == 0 {
+				count -= order
+			}
+			var sum uint64
+			for _, r := range residuals[pos : pos+count] {
+				sum += zigzag(r)
+			}
+			pos += count
+
+			k, kBits := bestRiceParam(sum, count)
+			params[i] = k
+			bits += 4 + kBits
+		}
+		if bits < bestBits {
+			bestOrder, bestBits, bestParams = p, bits, params
+		}
+	}
+	return bestOrder, bestParams, bestBits
+}
+
+// bestRiceParam estimates the Rice parameter for count values adding up to sum
+func bestRiceParam(sum uint64, count int) (int, int) {
+	bestK, bestBits := 0, math.MaxInt
+	for k := 0; k <= flacMaxRiceParam; k++ {
+		bits := count*(k+1) + int(sum>>k)
+		if bits < bestBits {
+			bestK, bestBits = k, bits
+		}
+	}
+	return bestK, bestBits
+}
+
+func zigzag(v int64) uint64 {
+	return uint64(v<<1) ^ uint64(v>>63)
+}
+
+// appendUTF8Number writes a frame number in FLAC's extended UTF-8 coding
+func appendUTF8Number(buf []byte, v uint64) []byte {
+	if v < 0x80 {
+		return append(buf, byte(v))
+	}
+
+	n := 2
+	for limit := uint64(0x800); v >= limit && n < 6; limit <<= 5 {
+		n++
+	}
+	prefix := byte(0xFF << (8 - n))
+	buf = append(buf, prefix|byte(v>>(6*(n-1))))
+	for i := n - 2; i >= 0; i-- {
+		buf = append(buf, 0x80|byte(v>>(6*i))&0x3F)
+	}
+	return buf
+}
+
+// bitWriter appends values MSB first
+type bitWriter struct {
+	buf   []byte
+	cur   byte
+	nbits uint
+}
+
+func (w *bitWriter) writeBits(v uint64, n uint) {
+	for n > 0 {
+		free := 8 - w.nbits
+		take := min(n, free)
+		chunk := byte(v>>(n-take)) & byte(1<<take-1)
+		w.cur |= chunk << (free - take)
+		w.nbits += take
+		n -= take
+		if w.nbits == 8 {
+			w.buf = append(w.buf, w.cur)
+			w.cur, w.nbits = 0, 0
+		}
+	}
+}
+
+func (w *bitWriter) writeRice(u uint64, k int) {
+	for q := u >> k; q > 0; {
+		zeros := min(q, 32)
+		w.writeBits(0, uint(zeros))
+		q -= zeros
+	}
+	w.writeBits(1, 1)
+	w.writeBits(u, uint(k))
+}
+
+// align pads the last byte with zero bits
+func (w *bitWriter) align() {
+	if w.nbits > 0 {
+		w.writeBits(0, 8-w.nbits)
+	}
+}
+
+func crc8(data []byte) byte {
+	var crc byte
+	for _, b := range data {
+		crc ^= b
+		for i := 0; i < 8; i++ {
+			if crc&0x80 != 0 {
+				crc = crc<<1 ^ 0x07
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+func crc16(data []byte) uint16 {
+	var crc uint16
+	for _, b := range data {
+		crc ^= uint16(b) << 8
+		for i := 0; i < 8; i++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x8005
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
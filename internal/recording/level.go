@@ -0,0 +1,34 @@
+package recording
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// Level is the loudness of an audio frame, normalized to 0.0-1.0
+type Level struct {
+	RMS  float64
+	Peak float64
+}
+
+// ComputeLevel measures RMS and peak of s16 little-endian samples
+func ComputeLevel(data []byte) Level {
+	samples := len(data) / 2
+	if samples == 0 {
+		return Level{}
+	}
+
+	var sum, peak float64
+	for i := 0; i < samples; i++ {
+		s := float64(int16(binary.LittleEndian.Uint16(data[i*2:]))) / 32768.0
+		sum += s * s
+		if a := math.Abs(s); a > peak {
+			peak = a
+		}
+	}
+
+	return Level{
+		RMS:  math.Sqrt(sum / float64(samples)),
+		Peak: peak,
+	}
+}
@@ -0,0 +1,163 @@
+package recording
+
+import (
+	"encoding/binary"
+	"math"
+	"sync"
+	"time"
+)
+
+// Recording inputs
+const (
+	InputMic     = "mic"     // the capture device (default)
+	InputMonitor = "monitor" // what a sink is playing
+	InputMixed   = "mixed"   // the microphone and the monitor summed into one stream
+)
+
+func inputOrMic(input string) string {
+	if input == "" {
+		return InputMic
+	}
+	return input
+}
+
+// mixLag is how far one input of a mixed capture may run ahead of the other
+// before its audio is passed through unmixed, so a stalled input never holds
+// back the recording
+const mixLag = 200 * time.Millisecond
+
+// frameSink receives captured audio frames
+type frameSink interface {
+	push(frame AudioFrame)
+}
+
+// monitorConfig returns the capture config for the monitor of the configured
+// sink. There is no failover list: when the sink goes away the default sink's
+// monitor is used.
+func (c Config) monitorConfig() Config {
+	c.Input = InputMonitor
+	c.Device = c.MonitorDevice
+	c.FallbackDevices = nil
+	return c
+}
+
+// pulseMonitorSource names the PulseAudio monitor source of a sink
+func pulseMonitorSource(sink string) string {
+	if sink == "" {
+		return "@DEFAULT_MONITOR@"
+	}
+	return sink + ".monitor"
+}
+
+// mixer sums two capture streams sample by sample into one sink
+type mixer struct {
+	mu        sync.Mutex // guards pending and keeps pushes to out in order
+	format    string
+	frameSize int
+	maxLag    int // bytes
+	pending   [2][]byte
+	out       frameSink
+}
+
+func newMixer(config Config, out frameSink) *mixer {
+	maxLag := int(mixLag.Seconds() * float64(config.bytesPerSecond()))
+	return &mixer{
+		format:    config.Format,
+		frameSize: max(1, config.frameSize()),
+		maxLag:    maxLag - maxLag%max(1, config.frameSize()),
+		out:       out,
+	}
+}
+
+// input returns the sink for one of the two mixed streams
+func (m *mixer) input(lane int) frameSink {
+	return mixerInput{mixer: m, lane: lane}
+}
+
+type mixerInput struct {
+	mixer *mixer
+	lane  int
+}
+
+func (in mixerInput) push(frame AudioFrame) {
+	in.mixer.write(in.lane, frame.Data)
+}
+
+func (m *mixer) write(lane int, data []byte) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.pending[lane] = append(m.pending[lane], data...)
+
+	n := min(len(m.pending[0]), len(m.pending[1]))
+	n -= n % m.frameSize
+	var out []byte
+	if n > 0 {
+		out = mixSamples(m.pending[0][:n], m.pending[1][:n], m.format)
+		m.pending[0], m.pending[1] = m.pending[0][n:], m.pending[1][n:]
+	}
+
+	if excess := len(m.pending[lane]) - m.maxLag; excess > 0 {
+		excess -= excess % m.frameSize
+		out = append(out, m.pending[lane][:excess]...)
+		m.pending[lane] = m.pending[lane][excess:]
+	}
+
+	m.emit(out)
+}
+
+// flush mixes whatever is left once both captures have ended
+func (m *mixer) flush() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	a, b := m.pending[0], m.pending[1]
+	if len(a) < len(b) {
+		a, b = b, a
+	}
+	n := len(b) - len(b)%m.frameSize
+	out := mixSamples(a[:n], b[:n], m.format)
+	out = append(out, a[n:len(a)-len(a)%m.frameSize]...)
+	m.pending[0], m.pending[1] = nil, nil
+
+	m.emit(out)
+}
+
+func (m *mixer) emit(data []byte) {
+	if len(data) > 0 {
+		m.out.push(AudioFrame{Data: data, Timestamp: time.Now(), Level: ComputeLevel(data)})
+	}
+}
+
+// mixSamples adds two equally long runs of interleaved samples, clipping
+// instead of wrapping around on overflow
+func mixSamples(a, b []byte, format string) []byte {
+	out := make([]byte, len(a))
+	size := SampleSize(format)
+	for i := 0; i+size <= len(a); i += size {
+		switch format {
+		case "s24":
+			v := clamp(int64(readS24(a[i:]))+int64(readS24(b[i:])), 1<<23)
+			out[i], out[i+1], out[i+2] = byte(v), byte(v>>8), byte(v>>16)
+		case "s32":
+			v := clamp(int64(int32(binary.LittleEndian.Uint32(a[i:])))+int64(int32(binary.LittleEndian.Uint32(b[i:]))), 1<<31)
+			binary.LittleEndian.PutUint32(out[i:], uint32(int32(v)))
+		case "f32":
+			v := math.Float32frombits(binary.LittleEndian.Uint32(a[i:])) + math.Float32frombits(binary.LittleEndian.Uint32(b[i:]))
+			binary.LittleEndian.PutUint32(out[i:], math.Float32bits(max(-1, min(1, v))))
+		default:
+			v := clamp(int64(int16(binary.LittleEndian.Uint16(a[i:])))+int64(int16(binary.LittleEndian.Uint16(b[i:]))), 1<<15)
+			binary.LittleEndian.PutUint16(out[i:], uint16(int16(v)))
+		}
+	}
+	return out
+}
+
+func readS24(b []byte) int32 {
+	return int32(b[0]) | int32(b[1])<<8 | int32(int8(b[2]))<<16
+}
+
+// clamp limits v to the range of a signed integer with the given magnitude
+func clamp(v, limit int64) int64 {
+	return max(-limit, min(limit-1, v))
+}
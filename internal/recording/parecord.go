@@ -0,0 +1,68 @@
+package recording
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"time"
+)
+
+type parecordSource struct{}
+
+func NewParecordSource() Source {
+	return &parecordSource{}
+}
+
+func (p *parecordSource) Name() string {
+	return "parecord"
+}
+
+func (p *parecordSource) Available(ctx context.Context) error {
+	if _, err := exec.LookPath("parecord"); err != nil {
+		return fmt.Errorf("parecord not found: %w (install pulseaudio-utils)", err)
+	}
+
+	// pactl is optional, but when present it tells us whether a server is reachable
+	if _, err := exec.LookPath("pactl"); err == nil {
+		checkCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+		defer cancel()
+		if err := exec.CommandContext(checkCtx, "pactl", "info").Run(); err != nil {
+			return fmt.Errorf("PulseAudio server not running or accessible: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (p *parecordSource) Command(ctx context.Context, config Config) *exec.Cmd {
+	return exec.CommandContext(ctx, "parecord", parecordArgs(config)...)
+}
+
+func parecordArgs(config Config) []string {
+	args := []string{
+		"--raw",
+		"--format=" + parecordFormat(config.Format),
+		"--rate=" + strconv.Itoa(config.SampleRate),
+		"--channels=" + strconv.Itoa(config.Channels),
+	}
+	if config.Input == InputMonitor {
+		args = append(args, "--device="+pulseMonitorSource(config.Device))
+	} else if config.Device != "" {
+		args = append(args, "--device="+config.Device)
+	}
+	return args
+}
+
+func parecordFormat(format string) string {
+	switch format {
+	case "s24":
+		return "s24le"
+	case "s32":
+		return "s32le"
+	case "f32":
+		return "float32le"
+	default:
+		return "s16le"
+	}
+}
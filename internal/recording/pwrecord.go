@@ -0,0 +1,43 @@
+package recording
+
+import (
+	"context"
+	"os/exec"
+	"strconv"
+)
+
+type pwRecordSource struct{}
+
+func NewPwRecordSource() Source {
+	return &pwRecordSource{}
+}
+
+func (p *pwRecordSource) Name() string {
+	return "pw-record"
+}
+
+func (p *pwRecordSource) Available(ctx context.Context) error {
+	return CheckPipeWireAvailable(ctx)
+}
+
+func (p *pwRecordSource) Command(ctx context.Context, config Config) *exec.Cmd {
+	return exec.CommandContext(ctx, "pw-record", pwRecordArgs(config)...)
+}
+
+func pwRecordArgs(config Config) []string {
+	args := []string{
+		"--format", config.Format,
+		"--rate", strconv.Itoa(config.SampleRate),
+		"--channels", strconv.Itoa(config.Channels),
+		"-", // stdout
+	}
+	if config.Input == InputMonitor {
+		// Capture streams with this property link to a sink's monitor ports,
+		// the default sink when no target is given
+		args = append(args, "-P", "{ stream.capture.sink = true }")
+	}
+	if config.Device != "" {
+		args = append(args, "--target", config.Device)
+	}
+	return args
+}
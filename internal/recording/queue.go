@@ -0,0 +1,111 @@
+package recording
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// CaptureStats describes how audio moved from the capture process to the consumer
+type CaptureStats struct {
+	Frames     int64 // frames read from the capture process
+	Bytes      int64 // bytes read from the capture process
+	Overruns   int64 // frames spilled to the queue because the consumer was behind
+	MaxBacklog int   // largest number of frames waiting in the queue at once
+	Dropped    int64 // frames never delivered because the recording was aborted
+}
+
+func (s CaptureStats) String() string {
+	return fmt.Sprintf("frames=%d bytes=%d overruns=%d backlog=%d dropped=%d",
+		s.Frames, s.Bytes, s.Overruns, s.MaxBacklog, s.Dropped)
+}
+
+// frameQueue is an unbounded FIFO between a capture process and its consumer.
+// A slow consumer delays frames instead of losing them.
+type frameQueue struct {
+	mu     sync.Mutex
+	frames []AudioFrame
+	closed bool
+	ready  chan struct{} // signalled when frames are pushed or the queue is closed
+
+	frameCount int64
+	byteCount  int64
+	overruns   int64
+	maxBacklog int
+}
+
+func newFrameQueue() *frameQueue {
+	return &frameQueue{ready: make(chan struct{}, 1)}
+}
+
+func (q *frameQueue) push(frame AudioFrame) {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return
+	}
+	if len(q.frames) > 0 {
+		q.overruns++
+	}
+	q.frames = append(q.frames, frame)
+	q.frameCount++
+	q.byteCount += int64(len(frame.Data))
+	q.maxBacklog = max(q.maxBacklog, len(q.frames))
+	q.mu.Unlock()
+
+	q.signal()
+}
+
+// close marks the end of the stream; frames already queued can still be popped
+func (q *frameQueue) close() {
+	q.mu.Lock()
+	q.closed = true
+	q.mu.Unlock()
+
+	q.signal()
+}
+
+// pop blocks until a frame is available. It returns false once the queue is
+// closed and drained, or when ctx is done.
+func (q *frameQueue) pop(ctx context.Context) (AudioFrame, bool) {
+	for {
+		q.mu.Lock()
+		if len(q.frames) > 0 {
+			frame := q.frames[0]
+			q.frames[0] = AudioFrame{}
+			q.frames = q.frames[1:]
+			q.mu.Unlock()
+			return frame, true
+		}
+		closed := q.closed
+		q.mu.Unlock()
+
+		if closed {
+			return AudioFrame{}, false
+		}
+
+		select {
+		case <-q.ready:
+		case <-ctx.Done():
+			return AudioFrame{}, false
+		}
+	}
+}
+
+func (q *frameQueue) stats() CaptureStats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return CaptureStats{
+		Frames:     q.frameCount,
+		Bytes:      q.byteCount,
+		Overruns:   q.overruns,
+		MaxBacklog: q.maxBacklog,
+	}
+}
+
+func (q *frameQueue) signal() {
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
@@ -7,35 +7,75 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"os"
 	"os/exec"
-	"strconv"
 	"sync"
 	"sync/atomic"
 	"time"
 )
 
+// captureStopGrace is how long a stopped capture process gets to flush its
+// output before it is killed
+const captureStopGrace = time.Second
+
 type AudioFrame struct {
 	Data      []byte
 	Timestamp time.Time
+	Level     Level
 }
 
 type Config struct {
+	Backends          []string // Ordered capture backends: "pw-record", "parecord", "arecord", "ffmpeg"
 	SampleRate        int
 	Channels          int
 	Format            string
 	BufferSize        int
 	Device            string
+	FallbackDevices   []string // Tried in order when the device disappears mid-recording, before the default source
+	Input             string   // InputMic, InputMonitor or InputMixed
+	MonitorDevice     string   // Sink whose output monitor and mixed inputs capture; empty for the default sink
 	ChannelBufferSize int
 	Timeout           time.Duration
+	VAD               VADConfig
+	Warm              bool          // Keep a capture process running between recordings
+	PreRoll           time.Duration // Audio kept from before the recording starts (warm mode only)
+	DSP               DSPConfig
+}
+
+// frameSize returns the size in bytes of one sample across all channels
+func (c Config) frameSize() int {
+	return SampleSize(c.Format) * c.Channels
+}
+
+func (c Config) bytesPerSecond() int {
+	return c.frameSize() * c.SampleRate
+}
+
+// SampleSize returns the size in bytes of one sample in the given format
+func SampleSize(format string) int {
+	switch format {
+	case "s24":
+		return 3
+	case "s32", "f32":
+		return 4
+	default:
+		return 2
+	}
 }
 
 type Recorder struct {
 	config    Config
 	recording atomic.Bool
+	warm      *WarmCapture
+	source    Source
+
+	mu             sync.Mutex // guards cancel, queue and onDeviceChange
+	cancel         context.CancelFunc
+	queue          *frameQueue
+	onDeviceChange func(from, to string)
 
-	mu     sync.Mutex // guards cmd and cancel
-	cmd    *exec.Cmd
-	cancel context.CancelFunc
+	delivered atomic.Int64
+	dropped   atomic.Int64
 
 	wg sync.WaitGroup
 }
@@ -44,6 +84,12 @@ func NewRecorder(config Config) *Recorder {
 	return &Recorder{config: config}
 }
 
+// NewWarmRecorder creates a recorder that reads from an already running warm
+// capture instead of launching its own capture process
+func NewWarmRecorder(config Config, warm *WarmCapture) *Recorder {
+	return &Recorder{config: config, warm: warm}
+}
+
 func (r *Recorder) IsRecording() bool {
 	return r.recording.Load()
 }
@@ -57,8 +103,25 @@ func (r *Recorder) Start(ctx context.Context) (<-chan AudioFrame, <-chan error,
 		return nil, nil, err
 	}
 
-	if err := CheckPipeWireAvailable(ctx); err != nil {
-		return nil, nil, fmt.Errorf("PipeWire not available: %w", err)
+	var queue *frameQueue
+	if r.warm != nil {
+		q, err := r.warm.subscribe(r.deviceChanged)
+		if err != nil {
+			return nil, nil, fmt.Errorf("warm capture not available: %w", err)
+		}
+		queue = q
+	} else {
+		if r.source == nil {
+			source, err := SelectSource(ctx, r.config.Backends)
+			if err != nil {
+				return nil, nil, err
+			}
+			r.source = source
+		}
+		if r.config.Input != InputMic && r.config.Input != "" && !capturesMonitor(r.source) {
+			return nil, nil, fmt.Errorf("%s cannot capture system audio, use pw-record, parecord or ffmpeg", r.source.Name())
+		}
+		queue = newFrameQueue()
 	}
 
 	recordingCtx, cancel := context.WithCancel(ctx)
@@ -68,15 +131,39 @@ func (r *Recorder) Start(ctx context.Context) (<-chan AudioFrame, <-chan error,
 
 	r.mu.Lock()
 	r.cancel = cancel
+	r.queue = queue
 	r.mu.Unlock()
+	r.delivered.Store(0)
+	r.dropped.Store(0)
 
 	r.recording.Store(true)
 	r.wg.Add(1)
-	go r.captureLoop(recordingCtx, frameCh, errCh)
+	if r.warm != nil {
+		go r.warmLoop(ctx, recordingCtx, queue, frameCh, errCh)
+	} else {
+		go r.captureLoop(ctx, recordingCtx, queue, frameCh, errCh)
+	}
 
 	return frameCh, errCh, nil
 }
 
+// Stats reports how the current or most recent recording moved audio from
+// the capture process to the consumer
+func (r *Recorder) Stats() CaptureStats {
+	r.mu.Lock()
+	queue := r.queue
+	r.mu.Unlock()
+
+	if queue == nil {
+		return CaptureStats{}
+	}
+	stats := queue.stats()
+	stats.Dropped = r.dropped.Load()
+	return stats
+}
+
+// Stop ends the recording after every frame the capture process produced has
+// been delivered. Cancel the context passed to Start to abort instead.
 func (r *Recorder) Stop() {
 	if !r.recording.Load() {
 		return
@@ -87,7 +174,7 @@ func (r *Recorder) Stop() {
 	r.wg.Wait()
 }
 
-func (r *Recorder) captureLoop(ctx context.Context, frameCh chan<- AudioFrame, errCh chan<- error) {
+func (r *Recorder) captureLoop(ctx, stopCtx context.Context, queue *frameQueue, frameCh chan<- AudioFrame, errCh chan<- error) {
 	defer func() {
 		close(frameCh)
 		close(errCh)
@@ -100,33 +187,103 @@ func (r *Recorder) captureLoop(ctx context.Context, frameCh chan<- AudioFrame, e
 		r.wg.Done()
 	}()
 
-	args := r.buildPwRecordArgs()
-	cmd := exec.CommandContext(ctx, "pw-record", args...)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer queue.close()
+		r.captureInputs(stopCtx, queue, errCh)
+	}()
+
+	r.deliver(ctx, queue, frameCh)
+	<-done
+}
+
+// captureInputs runs the captures the configured input needs. A mixed input
+// captures the microphone and the monitor side by side and sums them.
+func (r *Recorder) captureInputs(stopCtx context.Context, queue *frameQueue, errCh chan<- error) {
+	switch r.config.Input {
+	case InputMonitor:
+		r.captureDevices(stopCtx, r.config.monitorConfig(), queue, errCh)
+	case InputMixed:
+		mic := r.config
+		mic.Input = InputMic
+		m := newMixer(r.config, queue)
+
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			r.captureDevices(stopCtx, mic, m.input(0), errCh)
+		}()
+		go func() {
+			defer wg.Done()
+			r.captureDevices(stopCtx, r.config.monitorConfig(), m.input(1), errCh)
+		}()
+		wg.Wait()
+		m.flush()
+	default:
+		r.captureDevices(stopCtx, r.config, queue, errCh)
+	}
+}
+
+// captureDevices runs the capture process and, when it exits while the
+// recording is still running, moves on to the next device in priority order.
+// Audio from every device goes to the same sink.
+func (r *Recorder) captureDevices(stopCtx context.Context, config Config, sink frameSink, errCh chan<- error) {
+	devices := newDeviceList(config)
+	for {
+		err := r.capture(stopCtx, config, devices.current(), sink)
+		if stopCtx.Err() != nil {
+			return
+		}
+		if err == nil {
+			err = fmt.Errorf("%s exited", r.source.Name())
+		}
+
+		from := devices.current()
+		to, ok := devices.next()
+		if !ok {
+			r.emitErr(errCh, fmt.Errorf("capture from %s stopped and no fallback device is left: %w", deviceName(from), err))
+			r.requestCancel()
+			return
+		}
+
+		log.Printf("Recording: capture from %s stopped (%v), switching to %s", deviceName(from), err, deviceName(to))
+		select {
+		case <-stopCtx.Done():
+			return
+		case <-time.After(failoverDelay):
+		}
+		r.deviceChanged(from, to)
+	}
+}
+
+// capture runs one capture process for device until it exits or stopCtx is done
+func (r *Recorder) capture(stopCtx context.Context, config Config, device string, sink frameSink) error {
+	config.Device = device
+
+	// The capture process is interrupted rather than killed on stop so it can
+	// flush the audio it already captured before exiting
+	cmd := r.source.Command(stopCtx, config)
+	cmd.Cancel = func() error {
+		return cmd.Process.Signal(os.Interrupt)
+	}
+	cmd.WaitDelay = captureStopGrace
 
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
-		r.emitErr(errCh, fmt.Errorf("create stdout pipe: %w", err))
-		r.requestCancel()
-		return
+		return fmt.Errorf("create stdout pipe: %w", err)
 	}
 
 	stderr, err := cmd.StderrPipe()
 	if err != nil {
-		r.emitErr(errCh, fmt.Errorf("create stderr pipe: %w", err))
-		r.requestCancel()
-		return
+		return fmt.Errorf("create stderr pipe: %w", err)
 	}
 
 	if err := cmd.Start(); err != nil {
-		r.emitErr(errCh, fmt.Errorf("start pw-record: %w", err))
-		r.requestCancel()
-		return
+		return fmt.Errorf("start %s: %w", r.source.Name(), err)
 	}
 
-	r.mu.Lock()
-	r.cmd = cmd
-	r.mu.Unlock()
-
 	go func() {
 		scanner := bufio.NewScanner(stderr)
 		for scanner.Scan() {
@@ -134,50 +291,96 @@ func (r *Recorder) captureLoop(ctx context.Context, frameCh chan<- AudioFrame, e
 		}
 	}()
 
+	readErr := readFrames(stdout, r.config.BufferSize, sink)
+	waitErr := cmd.Wait()
+
+	if readErr != nil {
+		return fmt.Errorf("read audio: %w", readErr)
+	}
+	return waitErr
+}
+
+// readFrames copies the capture process output into sink until EOF
+func readFrames(stdout io.Reader, bufferSize int, sink frameSink) error {
+	buffer := make([]byte, bufferSize)
+	for {
+		n, readErr := stdout.Read(buffer)
+		if n > 0 {
+			frameData := make([]byte, n)
+			copy(frameData, buffer[:n])
+			sink.push(AudioFrame{Data: frameData, Timestamp: time.Now(), Level: ComputeLevel(frameData)})
+		}
+
+		if readErr != nil {
+			if errors.Is(readErr, io.EOF) || errors.Is(readErr, os.ErrClosed) {
+				return nil
+			}
+			return readErr
+		}
+	}
+}
+
+// deliver forwards every queued frame to frameCh until the queue is closed and
+// drained. Only aborting ctx gives up on frames; they are counted as dropped.
+func (r *Recorder) deliver(ctx context.Context, queue *frameQueue, frameCh chan<- AudioFrame) {
 	for {
+		frame, ok := queue.pop(ctx)
+		if !ok {
+			break
+		}
+
 		select {
+		case frameCh <- frame:
+			r.delivered.Add(1)
 		case <-ctx.Done():
-			return
-		default:
-			buffer := make([]byte, r.config.BufferSize)
-			var sentCount int
-			var droppedCount int
-			lastDropLog := time.Now()
-			n, readErr := stdout.Read(buffer)
-			if n > 0 {
-				frameData := make([]byte, n)
-				copy(frameData, buffer[:n])
-
-				frame := AudioFrame{Data: frameData, Timestamp: time.Now()}
-
-				select {
-				case frameCh <- frame:
-					sentCount++
-				case <-ctx.Done():
-					return
-				default:
-					droppedCount++
-					if time.Since(lastDropLog) > time.Second {
-						log.Printf("Recording: dropped %d frames due to backpressure", droppedCount)
-						lastDropLog = time.Now()
-						droppedCount = 0
-					}
-				}
-			}
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
 
-			if readErr != nil {
-				if errors.Is(readErr, io.EOF) {
-					return
-				}
-				r.emitErr(errCh, fmt.Errorf("read audio: %w", readErr))
-				r.requestCancel()
-				return
+	if ctx.Err() != nil {
+		// Let the capture process finish so the dropped count covers everything it produced
+		for {
+			if _, ok := queue.pop(context.Background()); !ok {
+				break
 			}
-
+		}
+		if dropped := queue.stats().Frames - r.delivered.Load(); dropped > 0 {
+			r.dropped.Store(dropped)
+			log.Printf("Recording: dropped %d frames after abort", dropped)
 		}
 	}
 }
 
+// warmLoop relays frames from the warm capture subscription until the recording is stopped
+func (r *Recorder) warmLoop(ctx, stopCtx context.Context, queue *frameQueue, frameCh chan<- AudioFrame, errCh chan<- error) {
+	defer func() {
+		close(frameCh)
+		close(errCh)
+		r.recording.Store(false)
+
+		r.mu.Lock()
+		r.cancel = nil
+		r.mu.Unlock()
+
+		r.wg.Done()
+	}()
+
+	// Stopping ends the subscription; frames already queued are still delivered
+	go func() {
+		<-stopCtx.Done()
+		r.warm.unsubscribe(queue)
+	}()
+
+	r.deliver(ctx, queue, frameCh)
+
+	if stopCtx.Err() == nil {
+		r.emitErr(errCh, fmt.Errorf("warm capture stopped"))
+		r.requestCancel()
+	}
+}
+
 func (r *Recorder) requestCancel() {
 	r.mu.Lock()
 	cancel := r.cancel
@@ -195,19 +398,6 @@ func (r *Recorder) emitErr(errCh chan<- error, err error) {
 	log.Printf("Recording error: %v", err)
 }
 
-func (r *Recorder) buildPwRecordArgs() []string {
-	args := []string{
-		"--format", r.config.Format,
-		"--rate", strconv.Itoa(r.config.SampleRate),
-		"--channels", strconv.Itoa(r.config.Channels),
-		"-", // stdout
-	}
-	if r.config.Device != "" {
-		args = append(args, "--target", r.config.Device)
-	}
-	return args
-}
-
 func CheckPipeWireAvailable(ctx context.Context) error {
 	if _, err := exec.LookPath("pw-record"); err != nil {
 		return fmt.Errorf("pw-record not found: %w (install pipewire-tools)", err)
@@ -238,6 +428,11 @@ func (r *Recorder) validateConfig() error {
 	if r.config.Format == "" {
 		return fmt.Errorf("invalid Format: empty")
 	}
+	switch r.config.Input {
+	case "", InputMic, InputMonitor, InputMixed:
+	default:
+		return fmt.Errorf("invalid Input: %q", r.config.Input)
+	}
 	// For s16, sample frame size is 2 bytes per sample per channel.
 	if r.config.Format == "s16" {
 		frameBytes := 2 * r.config.Channels
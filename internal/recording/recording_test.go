@@ -1,8 +1,16 @@
 package recording
 
 import (
+	"bytes"
 	"context"
+	"crypto/md5"
+	"encoding/binary"
+	"fmt"
+	"math"
 	"os"
+	"os/exec"
+	"strconv"
+	"sync"
 	"testing"
 	"time"
 )
@@ -152,7 +160,7 @@ func TestRecorder_ValidateConfig(t *testing.T) {
 	}
 }
 
-func TestRecorder_BuildPwRecordArgs(t *testing.T) {
+func TestPwRecordSource_Command(t *testing.T) {
 	tests := []struct {
 		name     string
 		config   Config
@@ -193,17 +201,17 @@ func TestRecorder_BuildPwRecordArgs(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			recorder := NewRecorder(tt.config)
-			args := recorder.buildPwRecordArgs()
+			cmd := NewPwRecordSource().Command(context.Background(), tt.config)
+			args := cmd.Args[1:]
 
 			if len(args) != len(tt.expected) {
-				t.Errorf("buildPwRecordArgs() returned %d args, want %d", len(args), len(tt.expected))
+				t.Errorf("Command() has %d args, want %d", len(args), len(tt.expected))
 				return
 			}
 
 			for i, arg := range args {
 				if arg != tt.expected[i] {
-					t.Errorf("buildPwRecordArgs()[%d] = %q, want %q", i, arg, tt.expected[i])
+					t.Errorf("Command() arg %d = %q, want %q", i, arg, tt.expected[i])
 				}
 			}
 		})
@@ -376,3 +384,1036 @@ func TestRecorder_Start_InvalidConfig(t *testing.T) {
 		t.Errorf("Start() should fail with invalid config")
 	}
 }
+
+func TestVAD_Process(t *testing.T) {
+	speech := make([]byte, 3200) // 100ms at 16kHz mono s16
+	for i := 0; i < len(speech); i += 2 {
+		speech[i+1] = 0x20 // 0x2000 = 8192, roughly 0.25 RMS
+	}
+	silence := make([]byte, 3200)
+
+	config := VADConfig{
+		Enabled:         true,
+		SilenceDuration: 300 * time.Millisecond,
+		Threshold:       0.02,
+		MinSpeech:       200 * time.Millisecond,
+	}
+
+	t.Run("silence without speech does not trigger", func(t *testing.T) {
+		vad := NewVAD(config, 16000, 1)
+		for i := 0; i < 20; i++ {
+			if vad.Process(silence) {
+				t.Fatalf("Process() triggered on leading silence at frame %d", i)
+			}
+		}
+	})
+
+	t.Run("trailing silence after speech triggers once", func(t *testing.T) {
+		vad := NewVAD(config, 16000, 1)
+		for i := 0; i < 3; i++ {
+			if vad.Process(speech) {
+				t.Fatalf("Process() triggered on speech frame %d", i)
+			}
+		}
+		triggeredAt := -1
+		for i := 0; i < 10; i++ {
+			if vad.Process(silence) {
+				if triggeredAt != -1 {
+					t.Fatalf("Process() triggered twice")
+				}
+				triggeredAt = i
+			}
+		}
+		if triggeredAt != 2 {
+			t.Errorf("Process() triggered at silence frame %d, want 2", triggeredAt)
+		}
+	})
+
+	t.Run("short speech does not trigger", func(t *testing.T) {
+		vad := NewVAD(config, 16000, 1)
+		vad.Process(speech)
+		for i := 0; i < 10; i++ {
+			if vad.Process(silence) {
+				t.Fatalf("Process() triggered after speech shorter than MinSpeech")
+			}
+		}
+	})
+}
+
+func TestRingBuffer(t *testing.T) {
+	tests := []struct {
+		name     string
+		size     int
+		writes   [][]byte
+		expected []byte
+	}{
+		{"empty", 4, nil, []byte{}},
+		{"partial", 4, [][]byte{{1, 2}}, []byte{1, 2}},
+		{"exact", 4, [][]byte{{1, 2}, {3, 4}}, []byte{1, 2, 3, 4}},
+		{"wraps", 4, [][]byte{{1, 2, 3}, {4, 5, 6}}, []byte{3, 4, 5, 6}},
+		{"oversized write", 4, [][]byte{{1, 2, 3, 4, 5, 6}}, []byte{3, 4, 5, 6}},
+		{"zero size", 0, [][]byte{{1, 2}}, []byte{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ring := newRingBuffer(tt.size)
+			for _, w := range tt.writes {
+				ring.write(w)
+			}
+
+			got := ring.snapshot()
+			if string(got) != string(tt.expected) {
+				t.Errorf("snapshot() = %v, want %v", got, tt.expected)
+			}
+
+			if again := ring.snapshot(); len(again) != 0 {
+				t.Errorf("snapshot() after snapshot = %v, want empty", again)
+			}
+		})
+	}
+}
+
+func TestWarmCapture_PreRollSize(t *testing.T) {
+	config := Config{
+		SampleRate:        16000,
+		Channels:          1,
+		Format:            "s16",
+		BufferSize:        8192,
+		ChannelBufferSize: 30,
+		PreRoll:           500 * time.Millisecond,
+	}
+
+	warm := NewWarmCapture(config)
+	if got := len(warm.ring.data); got != 16000 {
+		t.Errorf("ring size = %d, want 16000", got)
+	}
+}
+
+func TestWarmCapture_SubscribeWhenStopped(t *testing.T) {
+	warm := NewWarmCapture(Config{
+		SampleRate:        16000,
+		Channels:          1,
+		Format:            "s16",
+		BufferSize:        8192,
+		ChannelBufferSize: 30,
+		PreRoll:           500 * time.Millisecond,
+	})
+
+	if _, err := warm.subscribe(nil); err == nil {
+		t.Errorf("subscribe() should fail when warm capture is not running")
+	}
+
+	// Stop should be safe when not running
+	warm.Stop()
+}
+
+func TestComputeLevel(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		wantRMS  float64
+		wantPeak float64
+	}{
+		{"empty", nil, 0, 0},
+		{"silence", []byte{0, 0, 0, 0}, 0, 0},
+		{"full scale negative", []byte{0x00, 0x80, 0x00, 0x80}, 1, 1},
+		{"half scale square", []byte{0x00, 0x40, 0x00, 0xC0}, 0.5, 0.5},
+		{"mixed", []byte{0x00, 0x40, 0x00, 0x00}, 0.3536, 0.5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			level := ComputeLevel(tt.data)
+			if diff := level.RMS - tt.wantRMS; diff > 0.001 || diff < -0.001 {
+				t.Errorf("RMS = %.4f, want %.4f", level.RMS, tt.wantRMS)
+			}
+			if diff := level.Peak - tt.wantPeak; diff > 0.001 || diff < -0.001 {
+				t.Errorf("Peak = %.4f, want %.4f", level.Peak, tt.wantPeak)
+			}
+		})
+	}
+}
+
+func TestParsePwDump(t *testing.T) {
+	dump := `[
+	{"id": 30, "type": "PipeWire:Interface:Metadata", "props": {"metadata.name": "default"},
+	 "metadata": [
+		{"subject": 0, "key": "default.audio.sink", "value": {"name": "alsa_output.speakers"}},
+		{"subject": 0, "key": "default.audio.source", "value": {"name": "alsa_input.usb-headset"}}
+	 ]},
+	{"id": 41, "type": "PipeWire:Interface:Node", "info": {"props": {
+		"node.name": "alsa_input.pci-internal", "node.description": "Built-in Microphone", "media.class": "Audio/Source"}}},
+	{"id": 42, "type": "PipeWire:Interface:Node", "info": {"props": {
+		"node.name": "alsa_input.usb-headset", "node.description": "USB Headset", "media.class": "Audio/Source"}}},
+	{"id": 43, "type": "PipeWire:Interface:Node", "info": {"props": {
+		"node.name": "alsa_output.speakers", "node.description": "Speakers", "media.class": "Audio/Sink"}}},
+	{"id": 44, "type": "PipeWire:Interface:Node", "info": {"props": {
+		"node.name": "virtual-mic", "node.nick": "Virtual Mic", "media.class": "Audio/Source/Virtual"}}},
+	{"id": 45, "type": "PipeWire:Interface:Port", "info": {"props": {"port.name": "capture_FL"}}}
+]`
+
+	devices, err := parsePwDump([]byte(dump))
+	if err != nil {
+		t.Fatalf("parsePwDump() error = %v", err)
+	}
+
+	expected := []Device{
+		{ID: 41, Name: "alsa_input.pci-internal", Description: "Built-in Microphone"},
+		{ID: 42, Name: "alsa_input.usb-headset", Description: "USB Headset", Default: true},
+		{ID: 44, Name: "virtual-mic", Description: "Virtual Mic"},
+	}
+	if len(devices) != len(expected) {
+		t.Fatalf("parsePwDump() returned %d devices, want %d: %+v", len(devices), len(expected), devices)
+	}
+	for i, d := range devices {
+		if d != expected[i] {
+			t.Errorf("device %d = %+v, want %+v", i, d, expected[i])
+		}
+	}
+
+	if _, ok := FindDevice(devices, "alsa_input.usb-headset"); !ok {
+		t.Errorf("FindDevice() by name failed")
+	}
+	if d, ok := FindDevice(devices, "41"); !ok || d.Name != "alsa_input.pci-internal" {
+		t.Errorf("FindDevice() by id = %+v, %v", d, ok)
+	}
+	if _, ok := FindDevice(devices, "alsa_output.speakers"); ok {
+		t.Errorf("FindDevice() should not find sinks")
+	}
+
+	if _, err := parsePwDump([]byte("not json")); err == nil {
+		t.Errorf("parsePwDump() should fail on invalid JSON")
+	}
+}
+
+func TestSourceArgs(t *testing.T) {
+	config := Config{
+		SampleRate: 16000,
+		Channels:   1,
+		Format:     "s16",
+		Device:     "mic",
+	}
+	monitor := config
+	monitor.MonitorDevice = "speakers"
+	monitor.FallbackDevices = []string{"headset"}
+	monitor = monitor.monitorConfig()
+	defaultMonitor := Config{SampleRate: 16000, Channels: 1, Format: "s16", Device: "mic", Input: InputMonitor}.monitorConfig()
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected []string
+	}{
+		{"pw-record", pwRecordArgs(config), []string{
+			"--format", "s16", "--rate", "16000", "--channels", "1", "-", "--target", "mic",
+		}},
+		{"parecord", parecordArgs(config), []string{
+			"--raw", "--format=s16le", "--rate=16000", "--channels=1", "--device=mic",
+		}},
+		{"arecord", arecordArgs(config), []string{
+			"-q", "-t", "raw", "-f", "S16_LE", "-r", "16000", "-c", "1", "-D", "mic", "-",
+		}},
+		{"ffmpeg", ffmpegArgs(config), []string{
+			"-hide_banner", "-loglevel", "error", "-f", "pulse", "-i", "mic",
+			"-ac", "1", "-ar", "16000", "-f", "s16le", "-",
+		}},
+		{"ffmpeg alsa default", ffmpegArgs(Config{SampleRate: 48000, Channels: 2, Format: "f32", Device: "hw:1"}), []string{
+			"-hide_banner", "-loglevel", "error", "-f", "alsa", "-i", "hw:1",
+			"-ac", "2", "-ar", "48000", "-f", "f32le", "-",
+		}},
+		{"pw-record monitor", pwRecordArgs(monitor), []string{
+			"--format", "s16", "--rate", "16000", "--channels", "1", "-",
+			"-P", "{ stream.capture.sink = true }", "--target", "speakers",
+		}},
+		{"pw-record default monitor", pwRecordArgs(defaultMonitor), []string{
+			"--format", "s16", "--rate", "16000", "--channels", "1", "-",
+			"-P", "{ stream.capture.sink = true }",
+		}},
+		{"parecord monitor", parecordArgs(monitor), []string{
+			"--raw", "--format=s16le", "--rate=16000", "--channels=1", "--device=speakers.monitor",
+		}},
+		{"parecord default monitor", parecordArgs(defaultMonitor), []string{
+			"--raw", "--format=s16le", "--rate=16000", "--channels=1", "--device=@DEFAULT_MONITOR@",
+		}},
+		{"ffmpeg monitor", ffmpegArgs(monitor), []string{
+			"-hide_banner", "-loglevel", "error", "-f", "pulse", "-i", "speakers.monitor",
+			"-ac", "1", "-ar", "16000", "-f", "s16le", "-",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if len(tt.args) != len(tt.expected) {
+				t.Fatalf("args = %q, want %q", tt.args, tt.expected)
+			}
+			for i := range tt.args {
+				if tt.args[i] != tt.expected[i] {
+					t.Errorf("args[%d] = %q, want %q", i, tt.args[i], tt.expected[i])
+				}
+			}
+		})
+	}
+}
+
+func TestNewSource(t *testing.T) {
+	for _, name := range []string{"pw-record", "parecord", "arecord", "ffmpeg"} {
+		source, err := NewSource(name)
+		if err != nil {
+			t.Errorf("NewSource(%q) error = %v", name, err)
+			continue
+		}
+		if source.Name() != name {
+			t.Errorf("NewSource(%q).Name() = %q", name, source.Name())
+		}
+	}
+
+	if _, err := NewSource("oss"); err == nil {
+		t.Errorf("NewSource() should fail for unknown backend")
+	}
+}
+
+func TestSelectSource_NoneAvailable(t *testing.T) {
+	_, err := SelectSource(context.Background(), []string{"oss", "sndio"})
+	if err == nil {
+		t.Errorf("SelectSource() should fail when no backend is available")
+	}
+}
+
+func TestFrameQueue(t *testing.T) {
+	q := newFrameQueue()
+	for i := 0; i < 3; i++ {
+		q.push(AudioFrame{Data: []byte{byte(i), 0}})
+	}
+
+	if backlog := q.stats().MaxBacklog; backlog != 3 {
+		t.Errorf("stats().MaxBacklog = %d, want 3", backlog)
+	}
+
+	q.close()
+	q.push(AudioFrame{Data: []byte{9, 9}}) // ignored after close
+
+	for i := 0; i < 3; i++ {
+		frame, ok := q.pop(context.Background())
+		if !ok {
+			t.Fatalf("pop() %d returned false before queue drained", i)
+		}
+		if frame.Data[0] != byte(i) {
+			t.Errorf("pop() %d = %v, frames out of order", i, frame.Data)
+		}
+	}
+	if _, ok := q.pop(context.Background()); ok {
+		t.Errorf("pop() should return false once closed and drained")
+	}
+
+	stats := q.stats()
+	if stats.Frames != 3 || stats.Bytes != 6 || stats.Overruns != 2 || stats.MaxBacklog != 3 {
+		t.Errorf("stats() = %+v, want frames=3 bytes=6 overruns=2 backlog=3", stats)
+	}
+}
+
+func TestFrameQueue_PopCancelled(t *testing.T) {
+	q := newFrameQueue()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, ok := q.pop(ctx); ok {
+		t.Errorf("pop() should return false when ctx is done")
+	}
+}
+
+// testSource emits a fixed amount of audio and then exits
+type testSource struct {
+	bytes int
+}
+
+func (s *testSource) Name() string                        { return "test" }
+func (s *testSource) Available(ctx context.Context) error { return nil }
+func (s *testSource) Command(ctx context.Context, config Config) *exec.Cmd {
+	return exec.CommandContext(ctx, "head", "-c", strconv.Itoa(s.bytes), "/dev/zero")
+}
+
+func TestRecorder_LosslessWithSlowConsumer(t *testing.T) {
+	if _, err := exec.LookPath("head"); err != nil {
+		t.Skip("head not available")
+	}
+
+	const total = 64000
+	recorder := NewRecorder(Config{
+		SampleRate:        16000,
+		Channels:          1,
+		Format:            "s16",
+		BufferSize:        320,
+		ChannelBufferSize: 1,
+	})
+	recorder.source = &testSource{bytes: total}
+
+	frameCh, _, err := recorder.Start(context.Background())
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	// Wait for the capture process to finish before consuming so frames pile up
+	time.Sleep(200 * time.Millisecond)
+
+	received := 0
+	for frame := range frameCh {
+		received += len(frame.Data)
+	}
+	recorder.Stop()
+
+	if received != total {
+		t.Errorf("received %d bytes, want %d", received, total)
+	}
+
+	stats := recorder.Stats()
+	if stats.Bytes != total {
+		t.Errorf("Stats().Bytes = %d, want %d", stats.Bytes, total)
+	}
+	if stats.Dropped != 0 {
+		t.Errorf("Stats().Dropped = %d, want 0", stats.Dropped)
+	}
+	if stats.Overruns == 0 || stats.MaxBacklog <= 1 {
+		t.Errorf("Stats() = %+v, expected overruns with a slow consumer", stats)
+	}
+}
+
+// sineFrame returns s16 mono samples of a sine wave at the given amplitude (0.0-1.0)
+func sineFrame(freq, amplitude float64, sampleRate, samples int, offset float64) []byte {
+	data := make([]byte, samples*2)
+	for i := 0; i < samples; i++ {
+		v := offset + amplitude*math.Sin(2*math.Pi*freq*float64(i)/float64(sampleRate))
+		binary.LittleEndian.PutUint16(data[i*2:], uint16(int16(v*32767)))
+	}
+	return data
+}
+
+func TestDSP_Process(t *testing.T) {
+	const sampleRate = 16000
+
+	tests := []struct {
+		name    string
+		config  DSPConfig
+		input   []byte
+		wantMin float64
+		wantMax float64
+	}{
+		{
+			name:    "dc removal strips a constant offset",
+			config:  DSPConfig{DCRemoval: true},
+			input:   sineFrame(0, 0, sampleRate, sampleRate, 0.3),
+			wantMin: 0,
+			wantMax: 0.01,
+		},
+		{
+			name:    "high-pass attenuates hum",
+			config:  DSPConfig{HighPassCutoff: 200},
+			input:   sineFrame(30, 0.5, sampleRate, sampleRate, 0),
+			wantMin: 0,
+			wantMax: 0.02,
+		},
+		{
+			name:    "high-pass keeps speech frequencies",
+			config:  DSPConfig{HighPassCutoff: 80},
+			input:   sineFrame(1000, 0.5, sampleRate, sampleRate, 0),
+			wantMin: 0.33,
+			wantMax: 0.37,
+		},
+		{
+			name:    "normalize boosts quiet speech to the target",
+			config:  DSPConfig{Normalize: true, NormalizeTarget: 0.1, NormalizeMaxGain: 10},
+			input:   sineFrame(440, 0.05, sampleRate, sampleRate, 0),
+			wantMin: 0.09,
+			wantMax: 0.11,
+		},
+		{
+			name:    "normalize respects the maximum gain",
+			config:  DSPConfig{Normalize: true, NormalizeTarget: 0.1, NormalizeMaxGain: 2},
+			input:   sineFrame(440, 0.01, sampleRate, sampleRate, 0),
+			wantMin: 0.013,
+			wantMax: 0.015,
+		},
+		{
+			name:    "noise gate mutes background noise",
+			config:  DSPConfig{NoiseGate: true, NoiseGateThreshold: 0.05},
+			input:   sineFrame(440, 0.02, sampleRate, sampleRate, 0),
+			wantMin: 0,
+			wantMax: 0.001,
+		},
+		{
+			name:    "noise gate passes speech",
+			config:  DSPConfig{NoiseGate: true, NoiseGateThreshold: 0.05},
+			input:   sineFrame(440, 0.3, sampleRate, sampleRate, 0),
+			wantMin: 0.2,
+			wantMax: 0.22,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dsp := NewDSP(tt.config, sampleRate, 1)
+
+			// Feed the signal in small frames and measure the last one, after filters and gain settled
+			const frameBytes = 640
+			var last []byte
+			for i := 0; i+frameBytes <= len(tt.input); i += frameBytes {
+				last = dsp.Process(tt.input[i : i+frameBytes])
+			}
+
+			rms := ComputeLevel(last).RMS
+			if rms < tt.wantMin || rms > tt.wantMax {
+				t.Errorf("output RMS = %.4f, want between %.4f and %.4f", rms, tt.wantMin, tt.wantMax)
+			}
+		})
+	}
+}
+
+func TestDSPConfig_Enabled(t *testing.T) {
+	if (DSPConfig{}).Enabled() {
+		t.Errorf("zero DSPConfig should be disabled")
+	}
+	if !(DSPConfig{HighPassCutoff: 80}).Enabled() {
+		t.Errorf("DSPConfig with a high-pass cutoff should be enabled")
+	}
+}
+
+func TestEncodeWAV(t *testing.T) {
+	tests := []struct {
+		format     string
+		wantFormat uint16
+		wantBits   uint16
+	}{
+		{"s16", 1, 16},
+		{"s24", 1, 24},
+		{"s32", 1, 32},
+		{"f32", 3, 32},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			pcm := make([]byte, 1200)
+			wav := EncodeWAV(pcm, 48000, 2, tt.format)
+
+			if len(wav) != 44+len(pcm) || string(wav[:4]) != "RIFF" || string(wav[36:40]) != "data" {
+				t.Fatalf("EncodeWAV() produced a malformed header")
+			}
+			if got := binary.LittleEndian.Uint16(wav[20:]); got != tt.wantFormat {
+				t.Errorf("audio format = %d, want %d", got, tt.wantFormat)
+			}
+			if got := binary.LittleEndian.Uint16(wav[22:]); got != 2 {
+				t.Errorf("channels = %d, want 2", got)
+			}
+			if got := binary.LittleEndian.Uint32(wav[24:]); got != 48000 {
+				t.Errorf("sample rate = %d, want 48000", got)
+			}
+			if got := binary.LittleEndian.Uint16(wav[34:]); got != tt.wantBits {
+				t.Errorf("bits per sample = %d, want %d", got, tt.wantBits)
+			}
+			if got := binary.LittleEndian.Uint32(wav[28:]); got != 48000*2*uint32(tt.wantBits/8) {
+				t.Errorf("byte rate = %d", got)
+			}
+		})
+	}
+}
+
+// toneSamples encodes an interleaved sine wave in the given format, with the
+// same signal on every channel
+func toneSamples(format string, freq, amplitude float64, sampleRate, channels, frames int) []byte {
+	size := SampleSize(format)
+	data := make([]byte, frames*channels*size)
+	for f := 0; f < frames; f++ {
+		v := amplitude * math.Sin(2*math.Pi*freq*float64(f)/float64(sampleRate))
+		for ch := 0; ch < channels; ch++ {
+			b := data[(f*channels+ch)*size:]
+			switch format {
+			case "s24":
+				s := int32(v * (1<<23 - 1))
+				b[0], b[1], b[2] = byte(s), byte(s>>8), byte(s>>16)
+			case "s32":
+				binary.LittleEndian.PutUint32(b, uint32(int32(v*(1<<31-1))))
+			case "f32":
+				binary.LittleEndian.PutUint32(b, math.Float32bits(float32(v)))
+			default:
+				binary.LittleEndian.PutUint16(b, uint16(int16(v*32767)))
+			}
+		}
+	}
+	return data
+}
+
+func TestConverter(t *testing.T) {
+	tests := []struct {
+		name       string
+		format     string
+		sampleRate int
+		channels   int
+		freq       float64
+		wantRMS    float64
+	}{
+		{"s16 mono 16k is passed through", "s16", 16000, 1, 440, 0.354},
+		{"s16 stereo 48k", "s16", 48000, 2, 440, 0.354},
+		{"s24 mono 44.1k", "s24", 44100, 1, 1000, 0.354},
+		{"s32 stereo 16k", "s32", 16000, 2, 440, 0.354},
+		{"f32 stereo 48k", "f32", 48000, 2, 440, 0.354},
+		{"tone above the new nyquist is filtered", "f32", 48000, 1, 12000, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conv := NewConverter(Config{Format: tt.format, SampleRate: tt.sampleRate, Channels: tt.channels}, 16000, 1)
+			input := toneSamples(tt.format, tt.freq, 0.5, tt.sampleRate, tt.channels, tt.sampleRate)
+
+			// Odd chunk sizes split sample frames across calls
+			var out []byte
+			for i := 0; i < len(input); i += 1001 {
+				out = append(out, conv.Convert(input[i:min(i+1001, len(input))])...)
+			}
+			out = append(out, conv.Flush()...)
+
+			if got := len(out) / 2; got < 15990 || got > 16010 {
+				t.Errorf("converted %d samples, want about 16000", got)
+			}
+			// Skip the edges where the resampler starts and ends on silence
+			rms := ComputeLevel(out[2000 : len(out)-2000]).RMS
+			if math.Abs(rms-tt.wantRMS) > 0.01 {
+				t.Errorf("output RMS = %.4f, want %.4f", rms, tt.wantRMS)
+			}
+		})
+	}
+}
+
+func TestConverter_Passthrough(t *testing.T) {
+	conv := NewConverter(Config{Format: "s16", SampleRate: 16000, Channels: 1}, 16000, 1)
+	if !conv.Passthrough() {
+		t.Fatalf("s16 mono 16 kHz should not need conversion")
+	}
+	data := []byte{1, 2, 3}
+	if got := conv.Convert(data); &got[0] != &data[0] {
+		t.Errorf("passthrough Convert() should return its input")
+	}
+
+	if NewConverter(Config{Format: "s16", SampleRate: 16000, Channels: 2}, 16000, 1).Passthrough() {
+		t.Errorf("stereo input should need downmixing")
+	}
+	if NewConverter(Config{Format: "f32", SampleRate: 16000, Channels: 1}, 16000, 1).Passthrough() {
+		t.Errorf("f32 input should need conversion")
+	}
+}
+
+// bitReader reads values MSB first, for decoding in tests
+type bitReader struct {
+	data []byte
+	pos  int // in bits
+}
+
+func (r *bitReader) read(n int) uint64 {
+	var v uint64
+	for i := 0; i < n; i++ {
+		bit := r.data[r.pos/8] >> (7 - r.pos%8) & 1
+		v = v<<1 | uint64(bit)
+		r.pos++
+	}
+	return v
+}
+
+func (r *bitReader) readSigned(n int) int64 {
+	return int64(r.read(n)<<(64-n)) >> (64 - n)
+}
+
+// decodeFLAC decodes the subset of FLAC that EncodeFLAC produces back into
+// s16 little-endian samples, checking the frame checksums on the way
+func decodeFLAC(t *testing.T, data []byte) (pcm []byte, sampleRate, channels int) {
+	t.Helper()
+	if string(data[:4]) != "fLaC" {
+		t.Fatalf("missing fLaC marker")
+	}
+	r := &bitReader{data: data, pos: 32}
+	if last, kind, length := r.read(1), r.read(7), r.read(24); last != 1 || kind != 0 || length != 34 {
+		t.Fatalf("unexpected metadata block: last=%d type=%d length=%d", last, kind, length)
+	}
+	r.read(16 + 16 + 24 + 24)
+	sampleRate = int(r.read(20))
+	channels = int(r.read(3)) + 1
+	if bps := r.read(5) + 1; bps != 16 {
+		t.Fatalf("bits per sample = %d, want 16", bps)
+	}
+	total := int(r.read(36))
+	sum := data[r.pos/8 : r.pos/8+16]
+	r.pos += 128
+
+	decoded := 0
+	for decoded < total {
+		start := r.pos / 8
+		if sync := r.read(16); sync != 0xFFF8 {
+			t.Fatalf("bad frame sync %#x at byte %d", sync, start)
+		}
+		sizeCode := r.read(4)
+		r.read(4)
+		if got := int(r.read(4)) + 1; got != channels {
+			t.Fatalf("frame channels = %d, want %d", got, channels)
+		}
+		r.read(4)
+		// frame number, UTF-8 coded
+		first := r.read(8)
+		for mask := uint64(0x40); first&0x80 != 0 && first&mask != 0; mask >>= 1 {
+			r.read(8)
+		}
+		blockSize := 4096
+		if sizeCode == 0x7 {
+			blockSize = int(r.read(16)) + 1
+		}
+		if crc := byte(r.read(8)); crc != crc8(data[start:r.pos/8-1]) {
+			t.Fatalf("frame header CRC mismatch")
+		}
+
+		block := make([][]int64, channels)
+		for ch := range block {
+			block[ch] = decodeSubframe(t, r, blockSize)
+		}
+		if r.pos%8 != 0 {
+			r.pos += 8 - r.pos%8
+		}
+		if crc := uint16(r.read(16)); crc != crc16(data[start:r.pos/8-2]) {
+			t.Fatalf("frame CRC mismatch")
+		}
+
+		for i := 0; i < blockSize; i++ {
+			for ch := range block {
+				pcm = binary.LittleEndian.AppendUint16(pcm, uint16(int16(block[ch][i])))
+			}
+		}
+		decoded += blockSize
+	}
+
+	if got := md5.Sum(pcm); string(got[:]) != string(sum) {
+		t.Errorf("decoded audio does not match the STREAMINFO MD5")
+	}
+	return pcm, sampleRate, channels
+}
+
+func decodeSubframe(t *testing.T, r *bitReader, n int) []int64 {
+	t.Helper()
+	header := r.read(8)
+	kind := header >> 1 & 0x3F
+	out := make([]int64, 0, n)
+	switch {
+	case kind == 0:
+		v := r.readSigned(16)
+		for i := 0; i < n; i++ {
+			out = append(out, v)
+		}
+	case kind == 1:
+		for i := 0; i < n; i++ {
+			out = append(out, r.readSigned(16))
+		}
+	case kind&0x38 == 0x08:
+		order := int(kind & 0x7)
+		for i := 0; i < order; i++ {
+			out = append(out, r.readSigned(16))
+		}
+		if method := r.read(2); method != 0 {
+			t.Fatalf("unexpected residual coding method %d", method)
+		}
+		partitionOrder := int(r.read(4))
+		for p := 0; p < 1<<partitionOrder; p++ {
+			count := n >> partitionOrder
+			if p == 0 {
+				count -= order
+			}
+			k := int(r.read(4))
+			for i := 0; i < count; i++ {
+				q := 0
+				for r.read(1) == 0 {
+					q++
+				}
+				u := uint64(q)<<k | r.read(k)
+				res := int64(u>>1) ^ -int64(u&1)
+				s := out
+				j := len(s)
+				var pred int64
+				switch order {
+				case 1:
+					pred = s[j-1]
+				case 2:
+					pred = 2*s[j-1] - s[j-2]
+				case 3:
+					pred = 3*s[j-1] - 3*s[j-2] + s[j-3]
+				case 4:
+					pred = 4*s[j-1] - 6*s[j-2] + 4*s[j-3] - s[j-4]
+				}
+				out = append(out, pred+res)
+			}
+		}
+	default:
+		t.Fatalf("unexpected subframe type %#x", kind)
+	}
+	return out
+}
+
+func TestEncodeFLAC(t *testing.T) {
+	noise := make([]byte, 2*3000)
+	seed := uint32(1)
+	for i := 0; i < len(noise); i += 2 {
+		seed = seed*1664525 + 1013904223
+		binary.LittleEndian.PutUint16(noise[i:], uint16(seed>>16))
+	}
+
+	tests := []struct {
+		name       string
+		pcm        []byte
+		sampleRate int
+		channels   int
+		maxRatio   float64 // largest acceptable compressed/raw size
+	}{
+		{"mono speech-like tone", toneSamples("s16", 440, 0.3, 16000, 1, 16000), 16000, 1, 0.6},
+		{"stereo tone with partial last block", toneSamples("s16", 1000, 0.5, 48000, 2, 10000), 48000, 2, 0.7},
+		{"silence", make([]byte, 2*16000), 16000, 1, 0.05},
+		{"white noise falls back to verbatim", noise, 16000, 1, 1.05},
+		{"single sample", []byte{0x34, 0x12}, 16000, 1, 100},
+		{"empty", nil, 16000, 1, 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flac := EncodeFLAC(tt.pcm, tt.sampleRate, tt.channels)
+			pcm, sampleRate, channels := decodeFLAC(t, flac)
+
+			if !bytes.Equal(pcm, tt.pcm) {
+				t.Fatalf("decoded audio differs from the input (%d vs %d bytes)", len(pcm), len(tt.pcm))
+			}
+			if sampleRate != tt.sampleRate || channels != tt.channels {
+				t.Errorf("decoded layout %d Hz %d ch, want %d Hz %d ch", sampleRate, channels, tt.sampleRate, tt.channels)
+			}
+			if len(tt.pcm) > 0 {
+				if ratio := float64(len(flac)) / float64(len(tt.pcm)); ratio > tt.maxRatio {
+					t.Errorf("compressed to %.2f of the input, want at most %.2f", ratio, tt.maxRatio)
+				}
+			}
+		})
+	}
+}
+
+func TestFLACChecksums(t *testing.T) {
+	check := []byte("123456789")
+	if got := crc8(check); got != 0xF4 {
+		t.Errorf("crc8() = %#x, want 0xf4", got)
+	}
+	if got := crc16(check); got != 0xFEE8 {
+		t.Errorf("crc16() = %#x, want 0xfee8", got)
+	}
+}
+
+// deviceSource emits a fixed amount of audio on every device it is opened
+// with, as if each device was unplugged right after
+type deviceSource struct {
+	bytes int
+
+	mu      sync.Mutex
+	devices []string
+}
+
+func (s *deviceSource) Name() string                        { return "test" }
+func (s *deviceSource) Available(ctx context.Context) error { return nil }
+func (s *deviceSource) Command(ctx context.Context, config Config) *exec.Cmd {
+	s.mu.Lock()
+	s.devices = append(s.devices, config.Device)
+	s.mu.Unlock()
+	return exec.CommandContext(ctx, "head", "-c", strconv.Itoa(s.bytes), "/dev/zero")
+}
+
+func TestRecorder_DeviceFailover(t *testing.T) {
+	if _, err := exec.LookPath("head"); err != nil {
+		t.Skip("head not available")
+	}
+
+	const perDevice = 3200
+	recorder := NewRecorder(Config{
+		SampleRate:        16000,
+		Channels:          1,
+		Format:            "s16",
+		BufferSize:        320,
+		ChannelBufferSize: 10,
+		Device:            "usb-headset",
+		FallbackDevices:   []string{"laptop-mic", "usb-headset"},
+	})
+	source := &deviceSource{bytes: perDevice}
+	recorder.source = source
+
+	var switches []string
+	recorder.OnDeviceChange(func(from, to string) {
+		switches = append(switches, from+"->"+to)
+	})
+
+	frameCh, errCh, err := recorder.Start(context.Background())
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	received := 0
+	for frame := range frameCh {
+		received += len(frame.Data)
+	}
+
+	// Audio from every device ends up in the same stream
+	if received != 3*perDevice {
+		t.Errorf("received %d bytes, want %d", received, 3*perDevice)
+	}
+	if want := "[usb-headset laptop-mic ]"; fmt.Sprint(source.devices) != want {
+		t.Errorf("opened devices %q, want %s", source.devices, want)
+	}
+	if want := "[usb-headset->laptop-mic laptop-mic->]"; fmt.Sprint(switches) != want {
+		t.Errorf("switches = %q, want %s", switches, want)
+	}
+	if err := <-errCh; err == nil {
+		t.Errorf("expected an error once no fallback device is left")
+	}
+}
+
+func TestDecodeWAV(t *testing.T) {
+	for _, format := range []string{"s16", "s24", "s32", "f32"} {
+		t.Run(format, func(t *testing.T) {
+			pcm := toneSamples(format, 440, 0.5, 48000, 2, 480)
+			got, rate, channels, gotFormat, err := DecodeWAV(EncodeWAV(pcm, 48000, 2, format))
+			if err != nil {
+				t.Fatalf("DecodeWAV() error = %v", err)
+			}
+			if rate != 48000 || channels != 2 || gotFormat != format {
+				t.Errorf("DecodeWAV() layout = %d Hz %d ch %s, want 48000 Hz 2 ch %s", rate, channels, gotFormat, format)
+			}
+			if !bytes.Equal(got, pcm) {
+				t.Errorf("DecodeWAV() audio differs from the encoded audio")
+			}
+		})
+	}
+
+	t.Run("extensible format with extra chunks", func(t *testing.T) {
+		pcm := toneSamples("s16", 440, 0.5, 16000, 1, 160)
+		wav := EncodeWAV(pcm, 16000, 1, "s16")
+
+		// Rewrite the fmt chunk as WAVE_FORMAT_EXTENSIBLE and put a LIST chunk before the data
+		fmtChunk := make([]byte, 8+40)
+		copy(fmtChunk, "fmt ")
+		binary.LittleEndian.PutUint32(fmtChunk[4:], 40)
+		copy(fmtChunk[8:], wav[20:36])
+		binary.LittleEndian.PutUint16(fmtChunk[8:], 0xFFFE)
+		binary.LittleEndian.PutUint16(fmtChunk[8+24:], 1)
+		list := append([]byte("LIST\x03\x00\x00\x00abc"), 0)
+
+		var file []byte
+		file = append(file, wav[:12]...)
+		file = append(file, fmtChunk...)
+		file = append(file, list...)
+		file = append(file, wav[36:]...)
+
+		got, rate, channels, format, err := DecodeWAV(file)
+		if err != nil {
+			t.Fatalf("DecodeWAV() error = %v", err)
+		}
+		if rate != 16000 || channels != 1 || format != "s16" || !bytes.Equal(got, pcm) {
+			t.Errorf("DecodeWAV() = %d bytes %d Hz %d ch %s", len(got), rate, channels, format)
+		}
+	})
+
+	t.Run("rejects other files", func(t *testing.T) {
+		if _, _, _, _, err := DecodeWAV([]byte("fLaC not a wav file")); err == nil {
+			t.Errorf("DecodeWAV() accepted a non-WAV file")
+		}
+		eightBit := EncodeWAV(make([]byte, 100), 8000, 1, "s16")
+		binary.LittleEndian.PutUint16(eightBit[34:], 8)
+		if _, _, _, _, err := DecodeWAV(eightBit); err == nil {
+			t.Errorf("DecodeWAV() accepted 8-bit audio")
+		}
+	})
+}
+
+func TestMixSamples(t *testing.T) {
+	s16 := func(values ...int16) []byte {
+		var out []byte
+		for _, v := range values {
+			out = binary.LittleEndian.AppendUint16(out, uint16(v))
+		}
+		return out
+	}
+	f32 := func(values ...float32) []byte {
+		var out []byte
+		for _, v := range values {
+			out = binary.LittleEndian.AppendUint32(out, math.Float32bits(v))
+		}
+		return out
+	}
+
+	tests := []struct {
+		name   string
+		format string
+		a, b   []byte
+		want   []byte
+	}{
+		{"s16 sum", "s16", s16(100, -200), s16(50, 50), s16(150, -150)},
+		{"s16 clips", "s16", s16(30000, -30000), s16(10000, -10000), s16(32767, -32768)},
+		{"s24 clips", "s24", []byte{0xFF, 0xFF, 0x7F}, []byte{0x01, 0x00, 0x00}, []byte{0xFF, 0xFF, 0x7F}},
+		{"f32 clips", "f32", f32(0.25, 0.75), f32(0.5, 0.5), f32(0.75, 1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mixSamples(tt.a, tt.b, tt.format); !bytes.Equal(got, tt.want) {
+				t.Errorf("mixSamples() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMixer(t *testing.T) {
+	config := Config{SampleRate: 1000, Channels: 1, Format: "s16"} // 200ms lag = 400 bytes
+	queue := newFrameQueue()
+	m := newMixer(config, queue)
+
+	level := func(v int16, samples int) []byte {
+		out := make([]byte, 0, samples*2)
+		for range samples {
+			out = binary.LittleEndian.AppendUint16(out, uint16(v))
+		}
+		return out
+	}
+
+	// Nothing comes out until both inputs have audio
+	m.input(0).push(AudioFrame{Data: level(100, 50)})
+	if queue.stats().Frames != 0 {
+		t.Fatalf("mixer emitted audio from a single input")
+	}
+	m.input(1).push(AudioFrame{Data: level(10, 30)})
+
+	// An input running ahead beyond the lag is passed through on its own
+	m.input(0).push(AudioFrame{Data: level(100, 400)})
+	m.flush()
+	queue.close()
+
+	var out []byte
+	for {
+		frame, ok := queue.pop(context.Background())
+		if !ok {
+			break
+		}
+		out = append(out, frame.Data...)
+	}
+
+	if len(out) != 450*2 {
+		t.Fatalf("mixed %d bytes, want %d", len(out), 450*2)
+	}
+	mixed := 0
+	for i := 0; i < len(out); i += 2 {
+		switch v := int16(binary.LittleEndian.Uint16(out[i:])); v {
+		case 110:
+			mixed++
+		case 100:
+		default:
+			t.Fatalf("sample %d = %d, want 110 or 100", i/2, v)
+		}
+	}
+	if mixed != 30 {
+		t.Errorf("%d samples mixed, want 30", mixed)
+	}
+	if int16(binary.LittleEndian.Uint16(out)) != 110 {
+		t.Errorf("the overlapping start should be mixed")
+	}
+}
@@ -0,0 +1,66 @@
+package recording
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os/exec"
+	"strings"
+)
+
+// Source represents an audio capture method that writes raw PCM to stdout
+type Source interface {
+	Name() string
+	Available(ctx context.Context) error
+	Command(ctx context.Context, config Config) *exec.Cmd
+}
+
+// DefaultBackends is used when no capture backends are configured
+var DefaultBackends = []string{"pw-record"}
+
+// NewSource returns the capture source registered under name
+func NewSource(name string) (Source, error) {
+	switch name {
+	case "pw-record":
+		return NewPwRecordSource(), nil
+	case "parecord":
+		return NewParecordSource(), nil
+	case "arecord":
+		return NewArecordSource(), nil
+	case "ffmpeg":
+		return NewFfmpegSource(), nil
+	default:
+		return nil, fmt.Errorf("unknown capture backend %q", name)
+	}
+}
+
+// capturesMonitor reports whether source can record what a sink is playing.
+// ALSA has no notion of sink monitors.
+func capturesMonitor(source Source) bool {
+	return source.Name() != "arecord"
+}
+
+// SelectSource returns the first available source from the ordered backend list
+func SelectSource(ctx context.Context, backends []string) (Source, error) {
+	if len(backends) == 0 {
+		backends = DefaultBackends
+	}
+
+	var failures []string
+	for _, name := range backends {
+		source, err := NewSource(name)
+		if err != nil {
+			log.Printf("Recording: %v, skipping", err)
+			failures = append(failures, err.Error())
+			continue
+		}
+		if err := source.Available(ctx); err != nil {
+			log.Printf("Recording: %s not available: %v, trying next backend", name, err)
+			failures = append(failures, fmt.Sprintf("%s: %v", name, err))
+			continue
+		}
+		return source, nil
+	}
+
+	return nil, fmt.Errorf("no audio capture backend available (%s)", strings.Join(failures, "; "))
+}
@@ -0,0 +1,60 @@
+package recording
+
+import "time"
+
+// VADConfig controls voice-activity-detection auto-stop
+type VADConfig struct {
+	Enabled         bool
+	SilenceDuration time.Duration // Trailing silence that ends the recording
+	Threshold       float64       // RMS energy (0.0-1.0) above which a frame counts as speech
+	MinSpeech       time.Duration // Speech required before silence can end the recording
+}
+
+// VAD detects trailing silence in a stream of s16 little-endian frames
+type VAD struct {
+	config     VADConfig
+	sampleRate int
+	channels   int
+
+	speech    time.Duration
+	silence   time.Duration
+	triggered bool
+}
+
+func NewVAD(config VADConfig, sampleRate, channels int) *VAD {
+	return &VAD{
+		config:     config,
+		sampleRate: sampleRate,
+		channels:   channels,
+	}
+}
+
+// Process feeds one frame to the detector and reports whether the recording
+// should stop. It returns true exactly once per detector.
+func (v *VAD) Process(data []byte) bool {
+	if v.triggered || len(data) < 2 {
+		return false
+	}
+
+	duration := v.frameDuration(len(data))
+	if ComputeLevel(data).RMS >= v.config.Threshold {
+		v.speech += duration
+		v.silence = 0
+		return false
+	}
+
+	v.silence += duration
+	if v.speech >= v.config.MinSpeech && v.silence >= v.config.SilenceDuration {
+		v.triggered = true
+		return true
+	}
+	return false
+}
+
+func (v *VAD) frameDuration(n int) time.Duration {
+	bytesPerSecond := 2 * v.channels * v.sampleRate
+	if bytesPerSecond <= 0 {
+		return 0
+	}
+	return time.Duration(n) * time.Second / time.Duration(bytesPerSecond)
+}
@@ -0,0 +1,307 @@
+package recording
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"time"
+)
+
+// warmRestartDelay is how long the warm capture waits before relaunching a capture process that exited
+const warmRestartDelay = time.Second
+
+// WarmCapture keeps a single capture process running for the lifetime of the
+// daemon. The most recent audio is kept in a ring buffer so a new recording
+// starts with pre-roll already in place instead of waiting for a capture process.
+type WarmCapture struct {
+	config Config
+	source Source
+
+	mu        sync.Mutex // guards ring, streamPos, sub, onDeviceChange and cancel
+	ring      *ringBuffer
+	streamPos int // bytes read from the current capture process
+	sub       *frameQueue
+
+	devices        *deviceList
+	onDeviceChange func(from, to string) // set by the current subscriber
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewWarmCapture(config Config) *WarmCapture {
+	if config.Input == InputMonitor {
+		config = config.monitorConfig()
+	}
+
+	size := int(config.PreRoll.Seconds() * float64(config.bytesPerSecond()))
+	if frameSize := config.frameSize(); frameSize > 0 {
+		size -= size % frameSize
+	}
+
+	return &WarmCapture{
+		config:  config,
+		ring:    newRingBuffer(size),
+		devices: newDeviceList(config),
+	}
+}
+
+// Start launches the background capture process. It keeps restarting the
+// process until ctx is cancelled or Stop is called.
+func (w *WarmCapture) Start(ctx context.Context) error {
+	if err := NewRecorder(w.config).validateConfig(); err != nil {
+		return err
+	}
+	if w.config.Input == InputMixed {
+		return fmt.Errorf("warm capture runs a single capture process and cannot mix inputs")
+	}
+
+	source, err := SelectSource(ctx, w.config.Backends)
+	if err != nil {
+		return err
+	}
+	if w.config.Input == InputMonitor && !capturesMonitor(source) {
+		return fmt.Errorf("%s cannot capture system audio", source.Name())
+	}
+
+	captureCtx, cancel := context.WithCancel(ctx)
+
+	w.mu.Lock()
+	if w.cancel != nil {
+		w.mu.Unlock()
+		cancel()
+		return fmt.Errorf("warm capture already running")
+	}
+	w.cancel = cancel
+	w.source = source
+	w.mu.Unlock()
+
+	w.wg.Add(1)
+	go w.run(captureCtx)
+
+	log.Printf("Warm capture: started %s with %v pre-roll", source.Name(), w.config.PreRoll)
+	return nil
+}
+
+// Records reports whether the warm capture records input, so a recording of
+// another input knows to start its own capture instead
+func (w *WarmCapture) Records(input string) bool {
+	return inputOrMic(w.config.Input) == inputOrMic(input)
+}
+
+func (w *WarmCapture) Stop() {
+	w.mu.Lock()
+	cancel := w.cancel
+	w.cancel = nil
+	w.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+	}
+	w.wg.Wait()
+
+	w.mu.Lock()
+	if w.sub != nil {
+		w.sub.close()
+		w.sub = nil
+	}
+	w.mu.Unlock()
+}
+
+// subscribe returns a queue that first receives the buffered pre-roll audio
+// and then every newly captured frame until unsubscribe is called. Only one
+// subscriber can be active at a time. onDeviceChange, if set, is called when
+// capture moves to another device during the subscription.
+func (w *WarmCapture) subscribe(onDeviceChange func(from, to string)) (*frameQueue, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.cancel == nil {
+		return nil, fmt.Errorf("warm capture not running")
+	}
+	if w.sub != nil {
+		return nil, fmt.Errorf("warm capture already has a subscriber")
+	}
+
+	sub := newFrameQueue()
+	preRoll := w.ring.snapshot()
+	// Drop a partial leading sample so the pre-roll lines up with the live stream
+	if frameSize := w.config.frameSize(); frameSize > 0 && len(preRoll) > 0 {
+		if rem := (w.streamPos - len(preRoll)) % frameSize; rem != 0 {
+			preRoll = preRoll[min(frameSize-rem, len(preRoll)):]
+		}
+	}
+	if len(preRoll) > 0 {
+		sub.push(AudioFrame{Data: preRoll, Timestamp: time.Now().Add(-w.config.PreRoll), Level: ComputeLevel(preRoll)})
+	}
+	w.sub = sub
+	w.onDeviceChange = onDeviceChange
+
+	return sub, nil
+}
+
+func (w *WarmCapture) unsubscribe(sub *frameQueue) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.sub != nil && w.sub == sub {
+		w.sub.close()
+		w.sub = nil
+		w.onDeviceChange = nil
+	}
+}
+
+func (w *WarmCapture) run(ctx context.Context) {
+	defer w.wg.Done()
+
+	for {
+		if err := w.capture(ctx); err != nil {
+			log.Printf("Warm capture: %v", err)
+		}
+
+		// The device most likely disappeared; once every fallback was tried,
+		// start over in case the preferred one is back
+		from := w.devices.current()
+		to, ok := w.devices.next()
+		if !ok {
+			w.devices.rewind()
+			to = w.devices.current()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(warmRestartDelay):
+			log.Printf("Warm capture: restarting capture process on %s", deviceName(to))
+		}
+
+		if to != from {
+			w.mu.Lock()
+			fn := w.onDeviceChange
+			w.mu.Unlock()
+			if fn != nil {
+				fn(from, to)
+			}
+		}
+	}
+}
+
+func (w *WarmCapture) capture(ctx context.Context) error {
+	config := w.config
+	config.Device = w.devices.current()
+	cmd := w.source.Command(ctx, config)
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("create stdout pipe: %w", err)
+	}
+
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return fmt.Errorf("create stderr pipe: %w", err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("start %s: %w", w.source.Name(), err)
+	}
+	defer cmd.Wait()
+
+	w.mu.Lock()
+	w.ring.snapshot() // discard pre-roll from a previous process
+	w.streamPos = 0
+	w.mu.Unlock()
+
+	go func() {
+		scanner := bufio.NewScanner(stderr)
+		for scanner.Scan() {
+			log.Printf("Warm capture stderr: %s", scanner.Text())
+		}
+	}()
+
+	buffer := make([]byte, w.config.BufferSize)
+	for {
+		n, readErr := stdout.Read(buffer)
+		if n > 0 {
+			frameData := make([]byte, n)
+			copy(frameData, buffer[:n])
+			w.dispatch(AudioFrame{Data: frameData, Timestamp: time.Now(), Level: ComputeLevel(frameData)})
+		}
+
+		if readErr != nil {
+			if errors.Is(readErr, io.EOF) || ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("read audio: %w", readErr)
+		}
+	}
+}
+
+func (w *WarmCapture) dispatch(frame AudioFrame) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.streamPos += len(frame.Data)
+	if w.sub == nil {
+		w.ring.write(frame.Data)
+		return
+	}
+
+	w.sub.push(frame)
+}
+
+// ringBuffer keeps the last size bytes written to it
+type ringBuffer struct {
+	data []byte
+	pos  int
+	full bool
+}
+
+func newRingBuffer(size int) *ringBuffer {
+	if size < 0 {
+		size = 0
+	}
+	return &ringBuffer{data: make([]byte, size)}
+}
+
+func (b *ringBuffer) write(p []byte) {
+	size := len(b.data)
+	if size == 0 {
+		return
+	}
+	if len(p) >= size {
+		copy(b.data, p[len(p)-size:])
+		b.pos = 0
+		b.full = true
+		return
+	}
+
+	n := copy(b.data[b.pos:], p)
+	if n < len(p) {
+		copy(b.data, p[n:])
+		b.full = true
+	}
+	b.pos = (b.pos + len(p)) % size
+	if b.pos == 0 {
+		b.full = true
+	}
+}
+
+// snapshot returns the buffered bytes in chronological order and empties the buffer
+func (b *ringBuffer) snapshot() []byte {
+	var out []byte
+	if b.full {
+		out = make([]byte, 0, len(b.data))
+		out = append(out, b.data[b.pos:]...)
+		out = append(out, b.data[:b.pos]...)
+	} else {
+		out = make([]byte, b.pos)
+		copy(out, b.data[:b.pos])
+	}
+	b.pos = 0
+	b.full = false
+	return out
+}
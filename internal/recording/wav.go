@@ -0,0 +1,110 @@
+package recording
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+const (
+	wavFormatPCM        = 1
+	wavFormatFloat      = 3
+	wavFormatExtensible = 0xFFFE
+)
+
+// EncodeWAV wraps raw little-endian audio in the given format ("s16", "s24",
+// "s32" or "f32") in a WAV container
+func EncodeWAV(pcm []byte, sampleRate, channels int, format string) []byte {
+	var buf bytes.Buffer
+
+	audioFormat := uint16(wavFormatPCM)
+	if format == "f32" {
+		audioFormat = wavFormatFloat
+	}
+	bytesPerSample := SampleSize(format)
+	blockAlign := bytesPerSample * channels
+	byteRate := blockAlign * sampleRate
+
+	buf.Grow(44 + len(pcm))
+
+	// RIFF header
+	buf.WriteString("RIFF")
+	binary.Write(&buf, binary.LittleEndian, uint32(36+len(pcm)))
+	buf.WriteString("WAVE")
+
+	// fmt chunk
+	buf.WriteString("fmt ")
+	binary.Write(&buf, binary.LittleEndian, uint32(16))
+	binary.Write(&buf, binary.LittleEndian, audioFormat)
+	binary.Write(&buf, binary.LittleEndian, uint16(channels))
+	binary.Write(&buf, binary.LittleEndian, uint32(sampleRate))
+	binary.Write(&buf, binary.LittleEndian, uint32(byteRate))
+	binary.Write(&buf, binary.LittleEndian, uint16(blockAlign))
+	binary.Write(&buf, binary.LittleEndian, uint16(bytesPerSample*8))
+
+	// data chunk
+	buf.WriteString("data")
+	binary.Write(&buf, binary.LittleEndian, uint32(len(pcm)))
+	buf.Write(pcm)
+
+	return buf.Bytes()
+}
+
+// DecodeWAV extracts the raw audio from a WAV file along with its layout.
+// Integer PCM with 16, 24 or 32 bits and 32-bit float are supported, matching
+// the formats a Converter accepts.
+func DecodeWAV(data []byte) (pcm []byte, sampleRate, channels int, format string, err error) {
+	if len(data) < 12 || string(data[:4]) != "RIFF" || string(data[8:12]) != "WAVE" {
+		return nil, 0, 0, "", fmt.Errorf("not a WAV file")
+	}
+
+	var haveFmt bool
+	for pos := 12; pos+8 <= len(data); {
+		id := string(data[pos : pos+4])
+		size := int(binary.LittleEndian.Uint32(data[pos+4:]))
+		body := data[pos+8 : min(pos+8+size, len(data))]
+		// Chunks are padded to an even size
+		pos += 8 + size + size%2
+
+		switch id {
+		case "fmt ":
+			if len(body) < 16 {
+				return nil, 0, 0, "", fmt.Errorf("truncated fmt chunk")
+			}
+			tag := binary.LittleEndian.Uint16(body)
+			channels = int(binary.LittleEndian.Uint16(body[2:]))
+			sampleRate = int(binary.LittleEndian.Uint32(body[4:]))
+			bits := binary.LittleEndian.Uint16(body[14:])
+			if tag == wavFormatExtensible && len(body) >= 26 {
+				// The real format tag starts the sub-format GUID
+				tag = binary.LittleEndian.Uint16(body[24:])
+			}
+
+			switch {
+			case tag == wavFormatPCM && bits == 16:
+				format = "s16"
+			case tag == wavFormatPCM && bits == 24:
+				format = "s24"
+			case tag == wavFormatPCM && bits == 32:
+				format = "s32"
+			case tag == wavFormatFloat && bits == 32:
+				format = "f32"
+			default:
+				return nil, 0, 0, "", fmt.Errorf("unsupported WAV encoding: format %d with %d bits", tag, bits)
+			}
+			haveFmt = true
+
+		case "data":
+			if !haveFmt {
+				return nil, 0, 0, "", fmt.Errorf("data chunk before fmt chunk")
+			}
+			if channels <= 0 || sampleRate <= 0 {
+				return nil, 0, 0, "", fmt.Errorf("invalid WAV layout: %d Hz, %d channels", sampleRate, channels)
+			}
+			frameSize := SampleSize(format) * channels
+			return body[:len(body)-len(body)%frameSize], sampleRate, channels, format, nil
+		}
+	}
+
+	return nil, 0, 0, "", fmt.Errorf("WAV file has no audio data")
+}
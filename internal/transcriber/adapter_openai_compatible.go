@@ -0,0 +1,130 @@
+package transcriber
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// Endpoints of an OpenAI-compatible server
+const (
+	EndpointTranscription = "transcription" // /audio/transcriptions, text in the spoken language (default)
+	EndpointTranslation   = "translation"   // /audio/translations, English text
+)
+
+// openAIPreset is a hosted provider that speaks the OpenAI audio API
+type openAIPreset struct {
+	name     string // prefix of errors and log lines
+	baseURL  string
+	endpoint string
+}
+
+// openAIPresets maps the hosted providers to their API settings
+var openAIPresets = map[string]openAIPreset{
+	"openai":                {name: "openai", baseURL: "https://api.openai.com/v1", endpoint: EndpointTranscription},
+	"groq-transcription":    {name: "groq", baseURL: "https://api.groq.com/openai/v1", endpoint: EndpointTranscription},
+	"groq-translation":      {name: "groq", baseURL: "https://api.groq.com/openai/v1", endpoint: EndpointTranslation},
+	"mistral-transcription": {name: "mistral", baseURL: "https://api.mistral.ai/v1", endpoint: EndpointTranscription},
+}
+
+// OpenAICompatibleAdapter implements TranscriptionAdapter for any server that
+// speaks the OpenAI audio API: the hosted presets as well as servers such as
+// LocalAI, faster-whisper-server or vLLM
+type OpenAICompatibleAdapter struct {
+	client *openai.Client
+	config Config
+	name   string
+}
+
+// NewOpenAICompatibleAdapter creates an adapter for config.BaseURL. A provider
+// with a preset uses the preset's base URL and endpoint, and no extra headers.
+func NewOpenAICompatibleAdapter(config Config) *OpenAICompatibleAdapter {
+	name := "openai-compatible"
+	if preset, ok := openAIPresets[config.Provider]; ok {
+		name = preset.name
+		config.BaseURL = preset.baseURL
+		config.Endpoint = preset.endpoint
+		config.Headers = nil
+	}
+
+	clientConfig := openai.DefaultConfig(config.APIKey)
+	clientConfig.BaseURL = config.BaseURL
+	var client openai.HTTPDoer = &http.Client{}
+	if len(config.Headers) > 0 {
+		client = headerClient{client: &http.Client{}, headers: config.Headers}
+	}
+	clientConfig.HTTPClient = statusClient{client: client}
+
+	return &OpenAICompatibleAdapter{
+		client: openai.NewClientWithConfig(clientConfig),
+		config: config,
+		name:   name,
+	}
+}
+
+func (a *OpenAICompatibleAdapter) Transcribe(ctx context.Context, audioData []byte) (string, error) {
+	return a.TranscribeWithPrompt(ctx, audioData, "")
+}
+
+func (a *OpenAICompatibleAdapter) TranscribeWithPrompt(ctx context.Context, audioData []byte, prompt string) (string, error) {
+	if len(audioData) == 0 {
+		return "", nil
+	}
+
+	// Pack raw PCM in the upload format
+	upload, filename, err := encodeUpload(ctx, audioData, a.config)
+	if err != nil {
+		return "", fmt.Errorf("encode audio: %w", err)
+	}
+
+	req := openai.AudioRequest{
+		Model:    a.config.Model,
+		Reader:   bytes.NewReader(upload),
+		FilePath: filename,
+		Language: a.config.Language, // Source language hint for translation
+		Prompt:   prompt,
+	}
+
+	start := time.Now()
+	var resp openai.AudioResponse
+	if a.config.Endpoint == EndpointTranslation {
+		resp, err = a.client.CreateTranslation(ctx, req)
+	} else {
+		resp, err = a.client.CreateTranscription(ctx, req)
+	}
+	duration := time.Since(start)
+
+	if err != nil {
+		log.Printf("%s-adapter: API call to %s failed after %v: %v", a.name, a.config.BaseURL, duration, err)
+		return "", fmt.Errorf("%s %s: %w", a.name, a.endpoint(), err)
+	}
+
+	log.Printf("%s-adapter: %s of %d bytes in %v: %q", a.name, a.endpoint(), len(audioData), duration, resp.Text)
+	return resp.Text, nil
+}
+
+func (a *OpenAICompatibleAdapter) endpoint() string {
+	if a.config.Endpoint == "" {
+		return EndpointTranscription
+	}
+	return a.config.Endpoint
+}
+
+// headerClient adds the configured headers to every request, after the
+// client's own so they can replace the Authorization header too
+type headerClient struct {
+	client  *http.Client
+	headers map[string]string
+}
+
+func (c headerClient) Do(req *http.Request) (*http.Response, error) {
+	for name, value := range c.headers {
+		req.Header.Set(name, value)
+	}
+	return c.client.Do(req)
+}
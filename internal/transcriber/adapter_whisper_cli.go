@@ -0,0 +1,139 @@
+package transcriber
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultWhisperCLI is the whisper.cpp command line binary used when none is configured
+const DefaultWhisperCLI = "whisper-cli"
+
+// WhisperCLIAdapter implements TranscriptionAdapter by running the whisper.cpp
+// command line tool against a local ggml model, so no server is needed
+type WhisperCLIAdapter struct {
+	binary string
+	config Config
+}
+
+// whisperCLIOutput is the part of whisper-cli's --output-json file we read
+type whisperCLIOutput struct {
+	Transcription []struct {
+		Text string `json:"text"`
+	} `json:"transcription"`
+}
+
+func NewWhisperCLIAdapter(config Config) *WhisperCLIAdapter {
+	binary := config.WhisperCLI
+	if binary == "" {
+		binary = DefaultWhisperCLI
+	}
+	return &WhisperCLIAdapter{
+		binary: binary,
+		config: config,
+	}
+}
+
+func (a *WhisperCLIAdapter) Transcribe(ctx context.Context, audioData []byte) (string, error) {
+	return a.TranscribeWithPrompt(ctx, audioData, "")
+}
+
+func (a *WhisperCLIAdapter) TranscribeWithPrompt(ctx context.Context, audioData []byte, prompt string) (string, error) {
+	if len(audioData) == 0 {
+		return "", nil
+	}
+
+	if _, err := exec.LookPath(a.binary); err != nil {
+		return "", fmt.Errorf("%s not found: install whisper.cpp or set transcription.whisper_cli", a.binary)
+	}
+
+	wavData, err := convertToWAV(audioData, a.config.SampleRate, a.config.Channels)
+	if err != nil {
+		return "", fmt.Errorf("convert to WAV: %w", err)
+	}
+
+	// whisper-cli reads its input and writes its output through files
+	dir, err := os.MkdirTemp("", "hyprvoice-whisper-*")
+	if err != nil {
+		return "", fmt.Errorf("create temp dir: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	input := filepath.Join(dir, "audio.wav")
+	if err := os.WriteFile(input, wavData, 0600); err != nil {
+		return "", fmt.Errorf("write temp audio: %w", err)
+	}
+	output := filepath.Join(dir, "transcript")
+
+	start := time.Now()
+	cmd := exec.CommandContext(ctx, a.binary, a.args(input, output, prompt)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		log.Printf("whisper-cli-adapter: %s failed after %v: %s", a.binary, time.Since(start), lastLines(stderr.String(), 5))
+		return "", fmt.Errorf("whisper-cli: %w", err)
+	}
+
+	data, err := os.ReadFile(output + ".json")
+	if err != nil {
+		return "", fmt.Errorf("read whisper-cli output: %w", err)
+	}
+	var result whisperCLIOutput
+	if err := json.Unmarshal(data, &result); err != nil {
+		return "", fmt.Errorf("decode whisper-cli output: %w", err)
+	}
+
+	parts := make([]string, 0, len(result.Transcription))
+	for _, segment := range result.Transcription {
+		parts = append(parts, segment.Text)
+	}
+	text := joinTranscripts(parts)
+
+	log.Printf("whisper-cli-adapter: transcribed %d bytes in %v: %q", len(audioData), time.Since(start), text)
+	return text, nil
+}
+
+// args builds the whisper-cli command line. The language defaults to
+// English in whisper-cli, so an empty one asks for detection explicitly.
+func (a *WhisperCLIAdapter) args(input, output, prompt string) []string {
+	language := a.config.Language
+	if language == "" {
+		language = "auto"
+	}
+
+	args := []string{
+		"--model", a.config.ModelPath,
+		"--file", input,
+		"--language", language,
+		"--output-json",
+		"--output-file", output,
+		"--no-prints",
+	}
+	if a.config.Threads > 0 {
+		args = append(args, "--threads", strconv.Itoa(a.config.Threads))
+	}
+	if a.config.Translate {
+		args = append(args, "--translate")
+	}
+	if prompt != "" {
+		args = append(args, "--prompt", prompt)
+	}
+	return args
+}
+
+// lastLines returns the last n lines of s, where tools put the error
+func lastLines(s string, n int) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
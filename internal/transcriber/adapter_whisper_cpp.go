@@ -5,7 +5,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"log"
 	"mime/multipart"
 	"net/http"
@@ -35,12 +34,25 @@ func NewWhisperCppAdapter(config Config) *WhisperCppAdapter {
 }
 
 func (a *WhisperCppAdapter) Transcribe(ctx context.Context, audioData []byte) (string, error) {
+	return a.TranscribeWithPrompt(ctx, audioData, "")
+}
+
+func (a *WhisperCppAdapter) TranscribeWithPrompt(ctx context.Context, audioData []byte, prompt string) (string, error) {
 	if len(audioData) == 0 {
 		return "", nil
 	}
 
+	// A managed server is started on first use and kept up while the request runs
+	if a.config.Server != nil {
+		release, err := a.config.Server.Acquire(ctx)
+		if err != nil {
+			return "", fmt.Errorf("start whisper.cpp server: %w", err)
+		}
+		defer release()
+	}
+
 	// Convert raw PCM to WAV format
-	wavData, err := convertToWAV(audioData)
+	wavData, err := convertToWAV(audioData, a.config.SampleRate, a.config.Channels)
 	if err != nil {
 		return "", fmt.Errorf("convert to WAV: %w", err)
 	}
@@ -65,6 +77,13 @@ func (a *WhisperCppAdapter) Transcribe(ctx context.Context, audioData []byte) (s
 		}
 	}
 
+	// Text preceding this audio, used as context
+	if prompt != "" {
+		if err := writer.WriteField("prompt", prompt); err != nil {
+			return "", fmt.Errorf("write prompt field: %w", err)
+		}
+	}
+
 	// Add parameters for better accuracy (matching whisper.cpp server API)
 	// temperature=0.0 makes output deterministic and more accurate
 	if err := writer.WriteField("temperature", "0.0"); err != nil {
@@ -103,9 +122,9 @@ func (a *WhisperCppAdapter) Transcribe(ctx context.Context, audioData []byte) (s
 
 	// Check status code
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		log.Printf("whisper-cpp-adapter: server returned status %d: %s", resp.StatusCode, string(body))
-		return "", fmt.Errorf("whisper.cpp server error: status %d", resp.StatusCode)
+		httpErr := newHTTPError(resp)
+		log.Printf("whisper-cpp-adapter: server returned %v", httpErr)
+		return "", fmt.Errorf("whisper.cpp server error: %w", httpErr)
 	}
 
 	// Parse response
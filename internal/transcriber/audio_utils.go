@@ -2,41 +2,101 @@ package transcriber
 
 import (
 	"bytes"
-	"encoding/binary"
+	"context"
+	"fmt"
+	"log"
+	"os/exec"
+
+	"github.com/leonardotrapani/hyprvoice/internal/recording"
+)
+
+// Audio layout recordings are converted to for every provider. They all run
+// Whisper-class models, which work on 16 kHz mono internally; sending more only
+// costs upload time, so the layout is fixed rather than chosen per provider.
+const (
+	DefaultSampleRate = 16000
+	DefaultChannels   = 1
+)
+
+// Upload formats
+const (
+	FormatWAV  = "wav"
+	FormatFLAC = "flac"
+	FormatOpus = "opus"
 )
 
-// convertToWAV converts raw 16-bit PCM audio to WAV format
-func convertToWAV(rawAudio []byte) ([]byte, error) {
-	var buf bytes.Buffer
-
-	const sampleRate = 16000
-	const channels = 1
-	const bitsPerSample = 16
-	const byteRate = sampleRate * channels * bitsPerSample / 8
-	const blockAlign = channels * bitsPerSample / 8
-
-	dataSize := len(rawAudio)
-	fileSize := 36 + dataSize
-
-	// WAV header
-	buf.WriteString("RIFF")
-	binary.Write(&buf, binary.LittleEndian, uint32(fileSize))
-	buf.WriteString("WAVE")
-
-	// fmt chunk
-	buf.WriteString("fmt ")
-	binary.Write(&buf, binary.LittleEndian, uint32(16))            // fmt chunk size
-	binary.Write(&buf, binary.LittleEndian, uint16(1))             // PCM format
-	binary.Write(&buf, binary.LittleEndian, uint16(channels))      // number of channels
-	binary.Write(&buf, binary.LittleEndian, uint32(sampleRate))    // sample rate
-	binary.Write(&buf, binary.LittleEndian, uint32(byteRate))      // byte rate
-	binary.Write(&buf, binary.LittleEndian, uint16(blockAlign))    // block align
-	binary.Write(&buf, binary.LittleEndian, uint16(bitsPerSample)) // bits per sample
-
-	// data chunk
-	buf.WriteString("data")
-	binary.Write(&buf, binary.LittleEndian, uint32(dataSize))
-	buf.Write(rawAudio)
-
-	return buf.Bytes(), nil
+// opusBitrate keeps speech intelligible for Whisper while being a fraction of FLAC
+const opusBitrate = "32k"
+
+// UploadFormat returns the container audio is sent to the provider in. WAV
+// is the default, since every provider reads it; FLAC and Opus are opt-in.
+// whisper.cpp only reads WAV.
+func UploadFormat(provider, requested string) string {
+	if provider == "whisper-cpp" || provider == "whisper-cli" || requested == "" {
+		return FormatWAV
+	}
+	return requested
+}
+
+// convertToWAV wraps raw 16-bit PCM audio with the given layout in a WAV container
+func convertToWAV(rawAudio []byte, sampleRate, channels int) ([]byte, error) {
+	if sampleRate <= 0 || channels <= 0 {
+		return nil, fmt.Errorf("invalid audio layout: %d Hz, %d channels", sampleRate, channels)
+	}
+	if len(rawAudio)%(2*channels) != 0 {
+		return nil, fmt.Errorf("audio length %d is not a whole number of %d-channel samples", len(rawAudio), channels)
+	}
+	return recording.EncodeWAV(rawAudio, sampleRate, channels, "s16"), nil
+}
+
+// encodeUpload packs raw 16-bit PCM audio in the configured upload format and
+// returns it with a file name whose extension tells the provider the format
+func encodeUpload(ctx context.Context, rawAudio []byte, config Config) ([]byte, string, error) {
+	wavData, err := convertToWAV(rawAudio, config.SampleRate, config.Channels)
+	if err != nil {
+		return nil, "", fmt.Errorf("convert to WAV: %w", err)
+	}
+
+	var data []byte
+	var filename string
+	switch config.UploadFormat {
+	case FormatOpus:
+		data, err = encodeOpus(ctx, wavData)
+		if err == nil {
+			filename = "audio.ogg"
+			break
+		}
+		log.Printf("transcriber: opus encoding failed, uploading FLAC instead: %v", err)
+		fallthrough
+	case FormatFLAC:
+		data = recording.EncodeFLAC(rawAudio, config.SampleRate, config.Channels)
+		filename = "audio.flac"
+	default:
+		return wavData, "audio.wav", nil
+	}
+
+	log.Printf("transcriber: encoded %d bytes of audio as %s (%d bytes, %.0f%%)",
+		len(wavData), filename, len(data), 100*float64(len(data))/float64(len(wavData)))
+	return data, filename, nil
+}
+
+// encodeOpus converts WAV audio to Opus in an Ogg container with ffmpeg, as
+// there is no pure Go Opus encoder
+func encodeOpus(ctx context.Context, wavData []byte) ([]byte, error) {
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		return nil, fmt.Errorf("ffmpeg not found")
+	}
+
+	cmd := exec.CommandContext(ctx, "ffmpeg", "-hide_banner", "-loglevel", "error",
+		"-f", "wav", "-i", "pipe:0",
+		"-c:a", "libopus", "-b:a", opusBitrate, "-application", "voip",
+		"-f", "ogg", "pipe:1")
+	cmd.Stdin = bytes.NewReader(wavData)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("ffmpeg: %w: %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	return stdout.Bytes(), nil
 }
@@ -0,0 +1,102 @@
+package transcriber
+
+import (
+	"math"
+	"strings"
+
+	"github.com/leonardotrapani/hyprvoice/internal/recording"
+)
+
+// DefaultMaxUploadSize stays under the 25 MB request limit of the OpenAI and
+// Groq audio endpoints
+const DefaultMaxUploadSize = 24 << 20
+
+// maxPromptChars keeps the context from the previous chunk well inside the
+// 224 token prompt Whisper accepts
+const maxPromptChars = 600
+
+// splitAudio cuts s16 audio into chunks of at most maxBytes. Each cut is made
+// in the longest quiet stretch of the second half of the chunk, so words are
+// not split between requests.
+func splitAudio(audio []byte, sampleRate, channels, maxBytes int) [][]byte {
+	frameBytes := channels * 2
+	maxBytes -= maxBytes % frameBytes
+	windowBytes := int(trimWindow.Seconds()*float64(sampleRate)) * frameBytes
+	if maxBytes <= 0 || windowBytes <= 0 || len(audio) <= maxBytes {
+		return [][]byte{audio}
+	}
+
+	var chunks [][]byte
+	for start := 0; start < len(audio); {
+		end := start + maxBytes
+		if end >= len(audio) {
+			chunks = append(chunks, audio[start:])
+			break
+		}
+
+		cut := quietestPoint(audio[start+maxBytes/2:end], windowBytes) + start + maxBytes/2
+		cut -= (cut - start) % frameBytes
+
+		chunks = append(chunks, audio[start:cut])
+		start = cut
+	}
+	return chunks
+}
+
+// quietestPoint returns the offset of the middle of the longest stretch of
+// the quietest windows in audio
+func quietestPoint(audio []byte, windowBytes int) int {
+	windows := len(audio) / windowBytes
+	if windows == 0 {
+		return len(audio)
+	}
+
+	levels := make([]float64, windows)
+	quietest := math.Inf(1)
+	for i := range levels {
+		levels[i] = recording.ComputeLevel(audio[i*windowBytes : (i+1)*windowBytes]).RMS
+		quietest = min(quietest, levels[i])
+	}
+
+	// Windows close to the minimum count as equally quiet
+	limit := quietest*2 + 0.001
+	bestStart, bestLen, runStart := 0, 0, -1
+	for i := 0; i <= windows; i++ {
+		if i < windows && levels[i] <= limit {
+			if runStart < 0 {
+				runStart = i
+			}
+			continue
+		}
+		if runStart >= 0 && i-runStart > bestLen {
+			bestStart, bestLen = runStart, i-runStart
+		}
+		runStart = -1
+	}
+	return (bestStart*2 + bestLen) * windowBytes / 2
+}
+
+// promptTail returns the end of text, starting at a word boundary, short
+// enough to be used as the prompt for the next chunk
+func promptTail(text string) string {
+	text = strings.TrimSpace(text)
+	if len(text) <= maxPromptChars {
+		return text
+	}
+	tail := text[len(text)-maxPromptChars:]
+	if i := strings.IndexByte(tail, ' '); i >= 0 {
+		tail = tail[i+1:]
+	}
+	return tail
+}
+
+// joinTranscripts stitches the text of consecutive chunks back together
+func joinTranscripts(parts []string) string {
+	var kept []string
+	for _, part := range parts {
+		if part = strings.TrimSpace(part); part != "" {
+			kept = append(kept, part)
+		}
+	}
+	return strings.Join(kept, " ")
+}
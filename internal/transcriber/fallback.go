@@ -0,0 +1,82 @@
+package transcriber
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+)
+
+// fallbackAdapter tries providers in order until one transcribes the audio
+type fallbackAdapter struct {
+	adapters  []TranscriptionAdapter
+	providers []string
+
+	mu   sync.Mutex
+	used []string // providers that produced text, in the order first used
+}
+
+func (a *fallbackAdapter) Transcribe(ctx context.Context, audioData []byte) (string, error) {
+	return a.TranscribeWithPrompt(ctx, audioData, "")
+}
+
+func (a *fallbackAdapter) TranscribeWithPrompt(ctx context.Context, audioData []byte, prompt string) (string, error) {
+	var errs []error
+	for i, adapter := range a.adapters {
+		if err := ctx.Err(); err != nil {
+			errs = append(errs, err)
+			break
+		}
+
+		var text string
+		var err error
+		if prompter, ok := adapter.(PromptAdapter); ok && prompt != "" {
+			text, err = prompter.TranscribeWithPrompt(ctx, audioData, prompt)
+		} else {
+			text, err = adapter.Transcribe(ctx, audioData)
+		}
+		if err == nil {
+			if i > 0 {
+				log.Printf("transcriber: transcribed with fallback provider %s", a.providers[i])
+			}
+			a.markUsed(a.providers[i])
+			return text, nil
+		}
+
+		log.Printf("transcriber: provider %s failed: %v", a.providers[i], err)
+		errs = append(errs, fmt.Errorf("%s: %w", a.providers[i], err))
+	}
+	return "", fmt.Errorf("all transcription providers failed: %w", errors.Join(errs...))
+}
+
+func (a *fallbackAdapter) markUsed(provider string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, used := range a.used {
+		if used == provider {
+			return
+		}
+	}
+	a.used = append(a.used, provider)
+}
+
+// provider names the providers that produced text, or the first one if
+// nothing was transcribed yet
+func (a *fallbackAdapter) provider() string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if len(a.used) == 0 {
+		return a.providers[0]
+	}
+	return strings.Join(a.used, "+")
+}
+
+// adapterProvider names the provider behind an adapter for a ProviderReporter
+func adapterProvider(adapter TranscriptionAdapter, config Config) string {
+	if chain, ok := adapter.(*fallbackAdapter); ok {
+		return chain.provider()
+	}
+	return config.Provider
+}
@@ -0,0 +1,83 @@
+package transcriber
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+
+	"github.com/leonardotrapani/hyprvoice/internal/recording"
+)
+
+// TranscribeFile transcribes an audio file with the configured provider,
+// going through the same trimming, chunking and upload encoding as a live
+// recording. WAV files are read directly; anything else is decoded by ffmpeg.
+func TranscribeFile(ctx context.Context, config Config, path string) (string, error) {
+	if config.SampleRate <= 0 || config.Channels <= 0 {
+		config.SampleRate, config.Channels = DefaultSampleRate, DefaultChannels
+	}
+
+	audio, err := loadAudioFile(ctx, path, config.SampleRate, config.Channels)
+	if err != nil {
+		return "", err
+	}
+
+	t, err := NewTranscriber(config)
+	if err != nil {
+		return "", fmt.Errorf("create transcriber: %w", err)
+	}
+
+	frameCh := make(chan recording.AudioFrame, 1)
+	frameCh <- recording.AudioFrame{Data: audio, Timestamp: time.Now()}
+	close(frameCh)
+
+	if _, err := t.Start(ctx, frameCh); err != nil {
+		return "", fmt.Errorf("start transcriber: %w", err)
+	}
+	if err := t.Stop(ctx); err != nil {
+		return "", err
+	}
+	return t.GetFinalTranscription()
+}
+
+// loadAudioFile returns the file's audio as s16 in the given layout
+func loadAudioFile(ctx context.Context, path string, sampleRate, channels int) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read audio file: %w", err)
+	}
+
+	if !bytes.HasPrefix(data, []byte("RIFF")) {
+		return decodeWithFFmpeg(ctx, path, sampleRate, channels)
+	}
+
+	pcm, rate, ch, format, err := recording.DecodeWAV(data)
+	if err != nil {
+		return nil, fmt.Errorf("decode %s: %w", path, err)
+	}
+	converter := recording.NewConverter(recording.Config{Format: format, SampleRate: rate, Channels: ch}, sampleRate, channels)
+	return append(converter.Convert(pcm), converter.Flush()...), nil
+}
+
+// decodeWithFFmpeg converts any format ffmpeg understands (FLAC, MP3, Ogg,
+// M4A, ...) to raw s16 audio
+func decodeWithFFmpeg(ctx context.Context, path string, sampleRate, channels int) ([]byte, error) {
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		return nil, fmt.Errorf("%s is not a WAV file and ffmpeg was not found to decode it", path)
+	}
+
+	cmd := exec.CommandContext(ctx, "ffmpeg", "-hide_banner", "-loglevel", "error",
+		"-i", path,
+		"-f", "s16le", "-ar", strconv.Itoa(sampleRate), "-ac", strconv.Itoa(channels),
+		"pipe:1")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("ffmpeg: %w: %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,279 @@
+package transcriber
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+const (
+	// retryAttempts is how often a request is sent before its error is returned
+	retryAttempts = 3
+	// retryBaseDelay is the wait before the first retry, doubled for each further one
+	retryBaseDelay = 500 * time.Millisecond
+	// retryMaxDelay caps the backoff between attempts
+	retryMaxDelay = 5 * time.Second
+	// retryBudget bounds the time spent on retries after the first request failed
+	retryBudget = 30 * time.Second
+)
+
+// ErrorKind classifies why a transcription request failed
+type ErrorKind string
+
+const (
+	ErrorAuth       ErrorKind = "auth"        // API key missing, invalid or without access
+	ErrorRateLimit  ErrorKind = "rate limit"  // too many requests or quota used up
+	ErrorServer     ErrorKind = "server"      // the provider failed to handle the request
+	ErrorNetwork    ErrorKind = "network"     // the provider could not be reached or timed out
+	ErrorBadRequest ErrorKind = "bad request" // the provider rejected the audio or settings
+	ErrorOther      ErrorKind = "other"
+)
+
+// Retryable reports whether a request that failed this way may succeed when sent again
+func (k ErrorKind) Retryable() bool {
+	return k == ErrorRateLimit || k == ErrorServer || k == ErrorNetwork
+}
+
+// ClassifyError returns the kind of a transcription error, looking through
+// wrapped and joined errors. Only timeouts and dropped or refused connections
+// count as network errors; TLS, DNS and URL errors do not go away on a retry.
+func ClassifyError(err error) ErrorKind {
+	var httpErr *HTTPError
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.Canceled):
+		return ErrorOther
+	case errors.As(err, &httpErr):
+		return httpErr.Kind()
+	case errors.Is(err, context.DeadlineExceeded),
+		errors.As(err, &netErr) && netErr.Timeout(),
+		errors.Is(err, io.ErrUnexpectedEOF),
+		errors.Is(err, syscall.ECONNRESET),
+		errors.Is(err, syscall.ECONNREFUSED):
+		return ErrorNetwork
+	}
+	return ErrorOther
+}
+
+// HTTPError is an error response from a provider
+type HTTPError struct {
+	StatusCode int
+	Message    string
+	RetryAfter time.Duration // from the Retry-After header, 0 when not sent
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("status %d: %s", e.StatusCode, e.Message)
+}
+
+// Kind classifies the response status
+func (e *HTTPError) Kind() ErrorKind {
+	switch {
+	case e.StatusCode == http.StatusUnauthorized, e.StatusCode == http.StatusPaymentRequired, e.StatusCode == http.StatusForbidden:
+		return ErrorAuth
+	case e.StatusCode == http.StatusTooManyRequests:
+		return ErrorRateLimit
+	case e.StatusCode == http.StatusRequestTimeout:
+		return ErrorNetwork
+	case e.StatusCode >= 500:
+		return ErrorServer
+	case e.StatusCode >= 400:
+		return ErrorBadRequest
+	}
+	return ErrorOther
+}
+
+// newHTTPError reads an error response. Providers put the reason in
+// differently shaped JSON bodies, so the common fields are tried in turn.
+func newHTTPError(resp *http.Response) *HTTPError {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+
+	var fields struct {
+		Error   json.RawMessage `json:"error"`
+		Message string          `json:"message"`
+		Detail  json.RawMessage `json:"detail"`
+	}
+	var message string
+	if json.Unmarshal(body, &fields) == nil {
+		var nested struct {
+			Message string `json:"message"`
+		}
+		var text string
+		switch {
+		case json.Unmarshal(fields.Error, &nested) == nil && nested.Message != "":
+			message = nested.Message
+		case json.Unmarshal(fields.Error, &text) == nil && text != "":
+			message = text
+		case fields.Message != "":
+			message = fields.Message
+		case json.Unmarshal(fields.Detail, &text) == nil && text != "":
+			message = text
+		}
+	}
+	if message == "" {
+		message = strings.TrimSpace(string(body))
+		if len(message) > 200 {
+			message = message[:200] + "..."
+		}
+	}
+	if message == "" {
+		message = http.StatusText(resp.StatusCode)
+	}
+
+	return &HTTPError{
+		StatusCode: resp.StatusCode,
+		Message:    message,
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+	}
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds or as a date
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+	return 0
+}
+
+// statusClient returns error responses as *HTTPError, so the OpenAI SDK
+// based adapters keep the status and Retry-After header for classification
+type statusClient struct {
+	client openai.HTTPDoer
+}
+
+func (c statusClient) Do(req *http.Request) (*http.Response, error) {
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 400 {
+		defer resp.Body.Close()
+		return nil, newHTTPError(resp)
+	}
+	return resp, nil
+}
+
+// retryAdapter sends a request again when it failed in a way that may be
+// temporary, waiting a jittered backoff or the provider's Retry-After. All
+// retries share a deadline of budget, so a provider that keeps failing cannot
+// hold up the session. With a fallback provider after it, rate limits are not
+// waited out: the fallback is tried at once instead.
+type retryAdapter struct {
+	adapter     TranscriptionAdapter
+	provider    string
+	hasFallback bool
+
+	attempts  int
+	baseDelay time.Duration
+	maxDelay  time.Duration
+	budget    time.Duration
+}
+
+func newRetryAdapter(adapter TranscriptionAdapter, provider string, hasFallback bool) *retryAdapter {
+	return &retryAdapter{
+		adapter:     adapter,
+		provider:    provider,
+		hasFallback: hasFallback,
+		attempts:    retryAttempts,
+		baseDelay:   retryBaseDelay,
+		maxDelay:    retryMaxDelay,
+		budget:      retryBudget,
+	}
+}
+
+func (a *retryAdapter) Transcribe(ctx context.Context, audioData []byte) (string, error) {
+	return a.TranscribeWithPrompt(ctx, audioData, "")
+}
+
+func (a *retryAdapter) TranscribeWithPrompt(ctx context.Context, audioData []byte, prompt string) (string, error) {
+	// The first request may take as long as it needs; the retries after it
+	// run under the budget, including their own requests
+	text, err := a.send(ctx, audioData, prompt)
+	if err == nil || a.attempts <= 1 || ctx.Err() != nil || !a.shouldRetry(err) {
+		return text, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, a.budget)
+	defer cancel()
+
+	for attempt := 1; ; attempt++ {
+		delay := a.delay(attempt, err)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			log.Printf("transcriber: no time left to retry %s (would wait %v)", a.provider, delay.Round(time.Millisecond))
+			return "", err
+		}
+
+		log.Printf("transcriber: %s %s error, retrying in %v (attempt %d/%d): %v",
+			a.provider, ClassifyError(err), delay.Round(time.Millisecond), attempt+1, a.attempts, err)
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return "", err
+		}
+
+		text, err = a.send(ctx, audioData, prompt)
+		if err == nil || attempt+1 >= a.attempts || ctx.Err() != nil || !a.shouldRetry(err) {
+			return text, err
+		}
+	}
+}
+
+func (a *retryAdapter) send(ctx context.Context, audioData []byte, prompt string) (string, error) {
+	if prompter, ok := a.adapter.(PromptAdapter); ok && prompt != "" {
+		return prompter.TranscribeWithPrompt(ctx, audioData, prompt)
+	}
+	return a.adapter.Transcribe(ctx, audioData)
+}
+
+// shouldRetry reports whether err is worth sending the request again for
+func (a *retryAdapter) shouldRetry(err error) bool {
+	kind := ClassifyError(err)
+	if !kind.Retryable() {
+		return false
+	}
+	var httpErr *HTTPError
+	if a.hasFallback && (kind == ErrorRateLimit || errors.As(err, &httpErr) && httpErr.RetryAfter > 0) {
+		log.Printf("transcriber: %s is rate limited, moving on to the fallback provider", a.provider)
+		return false
+	}
+	return true
+}
+
+// delay returns the wait before the next attempt. The provider's Retry-After
+// wins over the backoff.
+func (a *retryAdapter) delay(attempt int, err error) time.Duration {
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) && httpErr.RetryAfter > 0 {
+		return httpErr.RetryAfter
+	}
+
+	backoff := a.baseDelay << (attempt - 1)
+	if backoff > a.maxDelay || backoff <= 0 {
+		backoff = a.maxDelay
+	}
+	// Jitter in the upper half keeps clients that failed together apart
+	half := backoff / 2
+	return half + rand.N(half+1)
+}
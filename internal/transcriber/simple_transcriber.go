@@ -103,10 +103,14 @@ func (t *SimpleTranscriber) transcribeAll(ctx context.Context) error {
 		return nil
 	}
 
+	if t.config.Trim.Enabled && t.config.SampleRate > 0 && t.config.Channels > 0 {
+		audioData = t.trim(audioData)
+	}
+
 	log.Printf("transcriber: transcribing %d bytes of audio", len(audioData))
 
 	// Use the context passed from the pipeline for proper cancellation chain
-	text, err := t.adapter.Transcribe(ctx, audioData)
+	text, err := t.transcribe(ctx, audioData)
 	if err != nil {
 		log.Printf("transcriber: transcription failed: %v", err)
 		return fmt.Errorf("transcription failed: %w", err)
@@ -120,3 +124,107 @@ func (t *SimpleTranscriber) transcribeAll(ctx context.Context) error {
 
 	return nil
 }
+
+// transcribe sends the audio to the adapter, split into chunks when it is
+// larger than a single request may be
+func (t *SimpleTranscriber) transcribe(ctx context.Context, audioData []byte) (string, error) {
+	maxBytes := t.config.MaxUploadSize
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxUploadSize
+	}
+	// Leave room for the container, which never adds more than this to raw PCM
+	maxBytes -= maxBytes / 100
+
+	chunks := [][]byte{audioData}
+	if t.config.SampleRate > 0 && t.config.Channels > 0 {
+		chunks = splitAudio(audioData, t.config.SampleRate, t.config.Channels, maxBytes)
+	}
+	if len(chunks) == 1 {
+		return t.adapter.Transcribe(ctx, audioData)
+	}
+
+	log.Printf("transcriber: audio exceeds %d bytes, transcribing %d chunks", maxBytes, len(chunks))
+	if t.config.ChunkConcurrency > 1 {
+		return t.transcribeParallel(ctx, chunks)
+	}
+
+	// Sequential chunks see the end of the previous chunk's text as context
+	parts := make([]string, len(chunks))
+	prompter, canPrompt := t.adapter.(PromptAdapter)
+	for i, chunk := range chunks {
+		var text string
+		var err error
+		if canPrompt && i > 0 {
+			text, err = prompter.TranscribeWithPrompt(ctx, chunk, promptTail(parts[i-1]))
+		} else {
+			text, err = t.adapter.Transcribe(ctx, chunk)
+		}
+		if err != nil {
+			return "", fmt.Errorf("chunk %d/%d: %w", i+1, len(chunks), err)
+		}
+		parts[i] = text
+	}
+	return joinTranscripts(parts), nil
+}
+
+// transcribeParallel transcribes up to ChunkConcurrency chunks at a time and
+// cancels the remaining requests once one of them fails
+func (t *SimpleTranscriber) transcribeParallel(ctx context.Context, chunks [][]byte) (string, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	parts := make([]string, len(chunks))
+	sem := make(chan struct{}, t.config.ChunkConcurrency)
+	var wg sync.WaitGroup
+	var errOnce sync.Once
+	var firstErr error
+
+	for i, chunk := range chunks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			defer func() { <-sem }()
+
+			text, err := t.adapter.Transcribe(ctx, chunk)
+			if err != nil {
+				errOnce.Do(func() {
+					firstErr = fmt.Errorf("chunk %d/%d: %w", i+1, len(chunks), err)
+					cancel()
+				})
+				return
+			}
+			parts[i] = text
+		}()
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return "", firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	return joinTranscripts(parts), nil
+}
+
+// trim drops silence the provider would otherwise bill for and hallucinate on
+func (t *SimpleTranscriber) trim(audioData []byte) []byte {
+	bytesPerSecond := float64(t.config.SampleRate * t.config.Channels * 2)
+	trimmed := trimSilence(audioData, t.config.SampleRate, t.config.Channels, t.config.Trim)
+
+	removed := float64(len(audioData)-len(trimmed)) / bytesPerSecond
+	log.Printf("transcriber: removed %.1fs of silence (%.1fs -> %.1fs)",
+		removed, float64(len(audioData))/bytesPerSecond, float64(len(trimmed))/bytesPerSecond)
+
+	return trimmed
+}
+
+// Provider names the provider that produced the transcript
+func (t *SimpleTranscriber) Provider() string {
+	return adapterProvider(t.adapter, t.config)
+}
@@ -0,0 +1,355 @@
+package transcriber
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/leonardotrapani/hyprvoice/internal/recording"
+)
+
+const (
+	// DefaultStreamingPause is the silence that ends a segment when none is configured
+	DefaultStreamingPause = 800 * time.Millisecond
+
+	// streamMinSegment keeps segments long enough for Whisper to have context
+	streamMinSegment = 3 * time.Second
+	// streamMaxSegment cuts a segment without a pause at its quietest point;
+	// Whisper works on 30 second windows anyway
+	streamMaxSegment = 30 * time.Second
+	// defaultSpeechThreshold is used when no trim threshold is configured
+	defaultSpeechThreshold = 0.01
+)
+
+// StreamingTranscriber transcribes while the user is still talking. Audio is
+// cut into segments at pauses and every finished segment is sent to the
+// adapter right away, so at stop only the last segment is left.
+type StreamingTranscriber struct {
+	adapter TranscriptionAdapter
+	config  Config
+
+	segmenter *segmenter
+	queue     chan *segment
+
+	segmentsMu sync.Mutex
+	segments   []*segment // in recording order
+	emitted    int        // segments passed to onSegment so far
+	onSegment  func(text string)
+
+	// Control
+	running   bool
+	collectWg sync.WaitGroup
+	workerWg  sync.WaitGroup
+
+	// Transcription result
+	transcriptionMu   sync.RWMutex
+	transcriptionText string
+}
+
+// segment is a stretch of audio between two pauses and its transcript
+type segment struct {
+	index int
+	audio []byte
+	text  string
+	err   error
+	done  bool
+}
+
+func NewStreamingTranscriber(config Config, adapter TranscriptionAdapter) *StreamingTranscriber {
+	return &StreamingTranscriber{
+		adapter:   adapter,
+		config:    config,
+		segmenter: newSegmenter(config),
+	}
+}
+
+// OnSegment registers fn to be called with the text of each segment as soon
+// as it and every segment before it are transcribed
+func (t *StreamingTranscriber) OnSegment(fn func(text string)) {
+	t.segmentsMu.Lock()
+	defer t.segmentsMu.Unlock()
+	t.onSegment = fn
+}
+
+func (t *StreamingTranscriber) Start(ctx context.Context, frameCh <-chan recording.AudioFrame) (<-chan error, error) {
+	if t.running {
+		return nil, fmt.Errorf("transcriber already running")
+	}
+
+	t.running = true
+	t.queue = make(chan *segment, 16)
+
+	errCh := make(chan error, 1)
+
+	t.workerWg.Add(1)
+	go t.transcribeSegments(ctx)
+
+	t.collectWg.Add(1)
+	go t.collectAudio(ctx, frameCh, errCh)
+
+	return errCh, nil
+}
+
+// Stop transcribes the tail segment, retries segments that failed while
+// recording and joins everything into the final transcription
+func (t *StreamingTranscriber) Stop(ctx context.Context) error {
+	if !t.running {
+		return nil
+	}
+
+	t.collectWg.Wait()
+	t.enqueue(t.segmenter.flush())
+	close(t.queue)
+	t.workerWg.Wait()
+	t.running = false
+
+	t.segmentsMu.Lock()
+	segments := t.segments
+	t.segmentsMu.Unlock()
+
+	parts := make([]string, len(segments))
+	for i, seg := range segments {
+		if seg.err != nil {
+			log.Printf("transcriber: retrying segment %d after error: %v", seg.index+1, seg.err)
+			seg.text, seg.err = t.transcribeSegment(ctx, seg, "")
+			if seg.err != nil {
+				return fmt.Errorf("transcription failed: segment %d/%d: %w", seg.index+1, len(segments), seg.err)
+			}
+			t.finish(seg, seg.text)
+		}
+		parts[i] = seg.text
+	}
+
+	text := joinTranscripts(parts)
+	log.Printf("transcriber: transcription completed from %d segments: %q", len(segments), text)
+
+	t.transcriptionMu.Lock()
+	t.transcriptionText = text
+	t.transcriptionMu.Unlock()
+
+	return nil
+}
+
+func (t *StreamingTranscriber) GetFinalTranscription() (string, error) {
+	t.transcriptionMu.RLock()
+	defer t.transcriptionMu.RUnlock()
+	return t.transcriptionText, nil
+}
+
+// Provider names the provider that produced the transcript
+func (t *StreamingTranscriber) Provider() string {
+	return adapterProvider(t.adapter, t.config)
+}
+
+func (t *StreamingTranscriber) collectAudio(ctx context.Context, frameCh <-chan recording.AudioFrame, errCh chan<- error) {
+	defer func() {
+		close(errCh)
+		t.collectWg.Done()
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Printf("transcriber: stopping audio collection")
+			return
+
+		case frame, ok := <-frameCh:
+			if !ok {
+				log.Printf("transcriber: audio channel closed")
+				return
+			}
+
+			for _, audio := range t.segmenter.write(frame.Data) {
+				t.enqueue(audio)
+			}
+		}
+	}
+}
+
+// enqueue hands a finished segment to the worker, dropping segments without speech
+func (t *StreamingTranscriber) enqueue(audio []byte) {
+	if !hasSpeech(audio, t.segmenter.windowBytes, t.segmenter.threshold) {
+		return
+	}
+
+	t.segmentsMu.Lock()
+	seg := &segment{index: len(t.segments), audio: audio}
+	t.segments = append(t.segments, seg)
+	t.segmentsMu.Unlock()
+
+	t.queue <- seg
+}
+
+// transcribeSegments sends segments to the adapter one at a time, in order,
+// so each one can be given the text of the one before as context. A failed
+// segment is kept and retried at stop.
+func (t *StreamingTranscriber) transcribeSegments(ctx context.Context) {
+	defer t.workerWg.Done()
+
+	prompt := ""
+	for seg := range t.queue {
+		if err := ctx.Err(); err != nil {
+			seg.err = err
+			continue
+		}
+
+		text, err := t.transcribeSegment(ctx, seg, prompt)
+		if err != nil {
+			log.Printf("transcriber: segment %d failed, will retry at stop: %v", seg.index+1, err)
+			seg.err = err
+			prompt = ""
+			continue
+		}
+
+		log.Printf("transcriber: segment %d transcribed (%.1fs): %q", seg.index+1, t.duration(len(seg.audio)).Seconds(), text)
+		t.finish(seg, text)
+		prompt = promptTail(text)
+	}
+}
+
+// finish records a segment's text and passes on every segment that is now
+// final in order. A segment waiting for a retry holds back the ones after it.
+func (t *StreamingTranscriber) finish(seg *segment, text string) {
+	t.segmentsMu.Lock()
+	seg.text, seg.err, seg.done = text, nil, true
+
+	var ready []string
+	for t.emitted < len(t.segments) && t.segments[t.emitted].done {
+		if text := strings.TrimSpace(t.segments[t.emitted].text); text != "" {
+			ready = append(ready, text)
+		}
+		t.emitted++
+	}
+	fn := t.onSegment
+	t.segmentsMu.Unlock()
+
+	if fn != nil {
+		for _, text := range ready {
+			fn(text)
+		}
+	}
+}
+
+func (t *StreamingTranscriber) transcribeSegment(ctx context.Context, seg *segment, prompt string) (string, error) {
+	audio := seg.audio
+	if t.config.Trim.Enabled {
+		audio = trimSilence(audio, t.config.SampleRate, t.config.Channels, t.config.Trim)
+	}
+
+	if prompter, ok := t.adapter.(PromptAdapter); ok && prompt != "" {
+		return prompter.TranscribeWithPrompt(ctx, audio, prompt)
+	}
+	return t.adapter.Transcribe(ctx, audio)
+}
+
+func (t *StreamingTranscriber) duration(bytes int) time.Duration {
+	return time.Duration(bytes) * time.Second / time.Duration(t.config.SampleRate*t.config.Channels*2)
+}
+
+// segmenter cuts a stream of s16 audio into segments at pauses
+type segmenter struct {
+	windowBytes int
+	threshold   float64
+	pause       int // bytes of silence that end a segment
+	minSegment  int
+	maxSegment  int
+
+	buf     []byte
+	scanned int  // bytes of buf already classified, always whole windows
+	silence int  // bytes of silence at the end of the scanned audio
+	speech  bool // the scanned audio has a window above the threshold
+}
+
+func newSegmenter(config Config) *segmenter {
+	frameBytes := config.Channels * 2
+	bytesPerSecond := config.SampleRate * frameBytes
+	toBytes := func(d time.Duration) int {
+		n := int(d.Seconds() * float64(bytesPerSecond))
+		return n - n%frameBytes
+	}
+
+	threshold := config.Trim.Threshold
+	if threshold <= 0 {
+		threshold = defaultSpeechThreshold
+	}
+	pause := config.StreamingPause
+	if pause <= 0 {
+		pause = DefaultStreamingPause
+	}
+
+	return &segmenter{
+		windowBytes: max(frameBytes, toBytes(trimWindow)),
+		threshold:   threshold,
+		pause:       toBytes(pause),
+		minSegment:  toBytes(streamMinSegment),
+		maxSegment:  toBytes(streamMaxSegment),
+	}
+}
+
+// write adds audio and returns the segments it completed
+func (s *segmenter) write(data []byte) [][]byte {
+	s.buf = append(s.buf, data...)
+
+	var segments [][]byte
+	for s.scanned+s.windowBytes <= len(s.buf) {
+		window := s.buf[s.scanned : s.scanned+s.windowBytes]
+		s.scanned += s.windowBytes
+		if recording.ComputeLevel(window).RMS >= s.threshold {
+			s.speech = true
+			s.silence = 0
+		} else {
+			s.silence += s.windowBytes
+		}
+
+		switch {
+		case s.speech && s.silence >= s.pause && s.scanned >= s.minSegment:
+			// Cut in the middle of the pause so both sides keep some silence around their words
+			at := s.scanned - s.silence/2
+			segments = append(segments, s.cut(at-at%s.windowBytes))
+		case s.scanned >= s.maxSegment:
+			half := s.scanned / 2
+			at := half + quietestPoint(s.buf[half:s.scanned], s.windowBytes)
+			segments = append(segments, s.cut(at-at%s.windowBytes))
+		}
+	}
+	return segments
+}
+
+// cut returns the audio before at and keeps the rest for the next segment
+func (s *segmenter) cut(at int) []byte {
+	segment := s.buf[:at]
+	s.buf = append([]byte(nil), s.buf[at:]...)
+	s.scanned -= at
+
+	// Reclassify the scanned audio carried over into the next segment
+	s.speech, s.silence = false, 0
+	for i := 0; i+s.windowBytes <= s.scanned; i += s.windowBytes {
+		if recording.ComputeLevel(s.buf[i:i+s.windowBytes]).RMS >= s.threshold {
+			s.speech = true
+			s.silence = 0
+		} else {
+			s.silence += s.windowBytes
+		}
+	}
+	return segment
+}
+
+// flush returns whatever audio is left
+func (s *segmenter) flush() []byte {
+	rest := s.buf
+	s.buf, s.scanned, s.silence, s.speech = nil, 0, 0, false
+	return rest
+}
+
+// hasSpeech reports whether any window of audio is above the threshold
+func hasSpeech(audio []byte, windowBytes int, threshold float64) bool {
+	for i := 0; i < len(audio); i += windowBytes {
+		if recording.ComputeLevel(audio[i:min(i+windowBytes, len(audio))]).RMS >= threshold {
+			return true
+		}
+	}
+	return false
+}
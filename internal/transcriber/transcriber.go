@@ -3,6 +3,8 @@ package transcriber
 import (
 	"context"
 	"fmt"
+	"log"
+	"time"
 
 	"github.com/leonardotrapani/hyprvoice/internal/recording"
 )
@@ -14,11 +16,32 @@ type Transcriber interface {
 	GetFinalTranscription() (string, error)
 }
 
+// IncrementalTranscriber is implemented by transcribers that finish parts of
+// the transcript while the recording is still running
+type IncrementalTranscriber interface {
+	// OnSegment registers fn to be called with the text of every finalized
+	// segment, in recording order. Segments still pending are finalized by Stop.
+	OnSegment(fn func(text string))
+}
+
+// ProviderReporter is implemented by transcribers that can name the provider
+// that produced the transcript, which differs from the configured one when a
+// fallback provider was used
+type ProviderReporter interface {
+	Provider() string
+}
+
 // Adapter interface for different transcription backends
 type TranscriptionAdapter interface {
 	Transcribe(ctx context.Context, audioData []byte) (string, error)
 }
 
+// PromptAdapter is implemented by adapters that can be given the text spoken
+// before the audio, which keeps names and style consistent across chunks
+type PromptAdapter interface {
+	TranscribeWithPrompt(ctx context.Context, audioData []byte, prompt string) (string, error)
+}
+
 // Configuration for the transcriber
 type Config struct {
 	Provider  string
@@ -26,50 +49,143 @@ type Config struct {
 	Language  string
 	Model     string
 	ServerURL string // For local whisper.cpp server
+
+	Server *WhisperServer // For whisper-cpp: the managed server behind ServerURL, started on demand
+
+	BaseURL  string            // For openai-compatible: API root, e.g. http://localhost:8000/v1
+	Headers  map[string]string // For openai-compatible: sent with every request
+	Endpoint string            // For openai-compatible: EndpointTranscription or EndpointTranslation
+
+	ModelPath  string // For whisper-cli: ggml model file
+	WhisperCLI string // For whisper-cli: binary to run; empty uses DefaultWhisperCLI
+	Threads    int    // For whisper-cli: CPU threads; 0 uses whisper.cpp's default
+	Translate  bool   // For whisper-cli: translate to English
+
+	SampleRate int // Layout of the s16 audio handed to the transcriber, see DefaultSampleRate and DefaultChannels
+	Channels   int
+	Trim       TrimConfig
+
+	UploadFormat string // "wav", "flac" or "opus", see UploadFormat
+
+	MaxUploadSize    int // bytes per request, longer recordings are split; 0 uses DefaultMaxUploadSize
+	ChunkConcurrency int // chunks transcribed at once, 1 keeps them sequential with context
+
+	Streaming      bool          // transcribe segments during the recording, see StreamingTranscriber
+	StreamingPause time.Duration // silence that ends a segment; 0 uses DefaultStreamingPause
+
+	Fallbacks []Config // providers tried in order when this one fails; only their provider settings are used
+}
+
+// UseServer makes every whisper-cpp provider in the chain that talks to the
+// managed server start it on demand
+func (c *Config) UseServer(server *WhisperServer) {
+	if c.Provider == "whisper-cpp" && c.ServerURL == server.Config().URL() {
+		c.Server = server
+	}
+	for i := range c.Fallbacks {
+		c.Fallbacks[i].UseServer(server)
+	}
 }
 
 // NewTranscriber creates a new simple transcriber
 func NewTranscriber(config Config) (Transcriber, error) {
-	// Create the appropriate adapter
-	var adapter TranscriptionAdapter
+	config = normalizeConfig(config)
+
+	adapter, err := newAdapter(config)
+	if err != nil {
+		return nil, err
+	}
+	// Temporary failures are retried before a fallback provider is tried
+	adapter = newRetryAdapter(adapter, config.Provider, len(config.Fallbacks) > 0)
+
+	// Fallback providers are tried in order when the ones before them fail
+	if len(config.Fallbacks) > 0 {
+		chain := &fallbackAdapter{
+			adapters:  []TranscriptionAdapter{adapter},
+			providers: []string{config.Provider},
+		}
+		for i, fallback := range config.Fallbacks {
+			fallback.SampleRate, fallback.Channels = config.SampleRate, config.Channels
+			next, err := newAdapter(normalizeConfig(fallback))
+			if err != nil {
+				return nil, fmt.Errorf("fallback %d (%s): %w", i+1, fallback.Provider, err)
+			}
+			chain.adapters = append(chain.adapters, newRetryAdapter(next, fallback.Provider, i < len(config.Fallbacks)-1))
+			chain.providers = append(chain.providers, fallback.Provider)
+		}
+		adapter = chain
+	}
+
+	if config.Streaming {
+		return NewStreamingTranscriber(config, adapter), nil
+	}
+
+	// Create simple transcriber that collects all audio
+	transcriber := NewSimpleTranscriber(config, adapter)
 
+	return transcriber, nil
+}
+
+// normalizeConfig fills in the audio layout and the upload format the provider accepts
+func normalizeConfig(config Config) Config {
+	if config.SampleRate <= 0 || config.Channels <= 0 {
+		config.SampleRate, config.Channels = DefaultSampleRate, DefaultChannels
+	}
+	if format := UploadFormat(config.Provider, config.UploadFormat); format != config.UploadFormat {
+		if config.UploadFormat != "" {
+			log.Printf("transcriber: %s does not accept %s uploads, using %s", config.Provider, config.UploadFormat, format)
+		}
+		config.UploadFormat = format
+	}
+	return config
+}
+
+// newAdapter creates the adapter for the configured provider
+func newAdapter(config Config) (TranscriptionAdapter, error) {
 	switch config.Provider {
 	case "openai":
 		if config.APIKey == "" {
 			return nil, fmt.Errorf("OpenAI API key required")
 		}
-		adapter = NewOpenAIAdapter(config)
+		return NewOpenAICompatibleAdapter(config), nil
 
 	case "groq-transcription":
 		if config.APIKey == "" {
 			return nil, fmt.Errorf("Groq API key required")
 		}
-		adapter = NewGroqTranscriptionAdapter(config)
+		return NewOpenAICompatibleAdapter(config), nil
 
 	case "groq-translation":
 		if config.APIKey == "" {
 			return nil, fmt.Errorf("Groq API key required")
 		}
-		adapter = NewGroqTranslationAdapter(config)
+		return NewOpenAICompatibleAdapter(config), nil
 
 	case "mistral-transcription":
 		if config.APIKey == "" {
 			return nil, fmt.Errorf("Mistral API key required")
 		}
-		adapter = NewMistralAdapter(config)
+		return NewOpenAICompatibleAdapter(config), nil
 
 	case "whisper-cpp":
 		if config.ServerURL == "" {
 			return nil, fmt.Errorf("whisper.cpp server URL required")
 		}
-		adapter = NewWhisperCppAdapter(config)
+		return NewWhisperCppAdapter(config), nil
+
+	case "openai-compatible":
+		if config.BaseURL == "" {
+			return nil, fmt.Errorf("openai-compatible base URL required")
+		}
+		return NewOpenAICompatibleAdapter(config), nil
+
+	case "whisper-cli":
+		if config.ModelPath == "" {
+			return nil, fmt.Errorf("whisper-cli model path required")
+		}
+		return NewWhisperCLIAdapter(config), nil
 
 	default:
 		return nil, fmt.Errorf("unsupported provider: %s", config.Provider)
 	}
-
-	// Create simple transcriber that collects all audio
-	transcriber := NewSimpleTranscriber(config, adapter)
-
-	return transcriber, nil
 }
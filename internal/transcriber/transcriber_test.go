@@ -2,13 +2,58 @@ package transcriber
 
 import (
 	"context"
+	"crypto/x509"
+	"encoding/binary"
+	"errors"
 	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"testing"
 	"time"
 
 	"github.com/leonardotrapani/hyprvoice/internal/recording"
 )
 
+// TestMain lets the test binary stand in for whisper-server, see fakeWhisperServer
+func TestMain(m *testing.M) {
+	if os.Getenv("HYPRVOICE_FAKE_WHISPER_SERVER") != "" {
+		fakeWhisperServer()
+		return
+	}
+	os.Exit(m.Run())
+}
+
+// fakeWhisperServer serves the whisper.cpp server API on the --port it is given
+func fakeWhisperServer() {
+	var port string
+	for i, arg := range os.Args {
+		if arg == "--port" && i+1 < len(os.Args) {
+			port = os.Args[i+1]
+		}
+	}
+	http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"status": "ok"}`)
+	})
+	http.HandleFunc("/inference", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"text": "from the managed server"}`)
+	})
+	if err := http.ListenAndServe("127.0.0.1:"+port, nil); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
 func TestNewTranscriber(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -95,6 +140,38 @@ func TestNewTranscriber(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "valid openai-compatible config",
+			config: Config{
+				Provider: "openai-compatible",
+				BaseURL:  "http://localhost:8000/v1",
+				Model:    "Systran/faster-whisper-small",
+			},
+			wantErr: false,
+		},
+		{
+			name: "openai-compatible config without base url",
+			config: Config{
+				Provider: "openai-compatible",
+				Model:    "Systran/faster-whisper-small",
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid whisper-cli config",
+			config: Config{
+				Provider:  "whisper-cli",
+				ModelPath: "/models/ggml-base.en.bin",
+			},
+			wantErr: false,
+		},
+		{
+			name: "whisper-cli config without model path",
+			config: Config{
+				Provider: "whisper-cli",
+			},
+			wantErr: true,
+		},
 		{
 			name: "unsupported provider",
 			config: Config{
@@ -123,6 +200,29 @@ func TestNewTranscriber(t *testing.T) {
 			},
 			wantErr: false, // Model validation is not implemented in NewTranscriber
 		},
+		{
+			name: "with fallbacks",
+			config: Config{
+				Provider: "openai",
+				APIKey:   "test-key",
+				Model:    "whisper-1",
+				Fallbacks: []Config{
+					{Provider: "whisper-cpp", ServerURL: "http://127.0.0.1:8025/inference"},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "fallback without api key",
+			config: Config{
+				Provider:  "whisper-cpp",
+				ServerURL: "http://127.0.0.1:8025/inference",
+				Fallbacks: []Config{
+					{Provider: "openai", Model: "whisper-1"},
+				},
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -467,3 +567,1233 @@ func TestTranscriptionAdapter(t *testing.T) {
 		t.Errorf("Transcribe() = %q, want %q", result, "test result")
 	}
 }
+
+func TestFallbackAdapter(t *testing.T) {
+	failing := &MockTranscriptionAdapter{
+		TranscribeFunc: func(ctx context.Context, audioData []byte) (string, error) {
+			return "", errors.New("unavailable")
+		},
+	}
+	working := &MockTranscriptionAdapter{
+		TranscribeFunc: func(ctx context.Context, audioData []byte) (string, error) {
+			return "hello", nil
+		},
+	}
+
+	tests := []struct {
+		name         string
+		adapters     []TranscriptionAdapter
+		want         string
+		wantErr      bool
+		wantProvider string
+	}{
+		{"primary works", []TranscriptionAdapter{working, failing}, "hello", false, "first"},
+		{"falls back", []TranscriptionAdapter{failing, working}, "hello", false, "second"},
+		{"all fail", []TranscriptionAdapter{failing, failing}, "", true, "first"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			adapter := &fallbackAdapter{
+				adapters:  tt.adapters,
+				providers: []string{"first", "second"},
+			}
+
+			got, err := adapter.Transcribe(context.Background(), []byte{1, 2})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Transcribe() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Transcribe() = %q, want %q", got, tt.want)
+			}
+			if tt.wantErr && (!strings.Contains(err.Error(), "first: unavailable") || !strings.Contains(err.Error(), "second: unavailable")) {
+				t.Errorf("error %q does not name every provider", err)
+			}
+			if provider := adapter.provider(); provider != tt.wantProvider {
+				t.Errorf("provider() = %q, want %q", provider, tt.wantProvider)
+			}
+		})
+	}
+
+	t.Run("reports every provider used", func(t *testing.T) {
+		calls := 0
+		flaky := &MockTranscriptionAdapter{
+			TranscribeFunc: func(ctx context.Context, audioData []byte) (string, error) {
+				calls++
+				if calls == 2 {
+					return "", errors.New("unavailable")
+				}
+				return "chunk", nil
+			},
+		}
+		adapter := &fallbackAdapter{
+			adapters:  []TranscriptionAdapter{flaky, working},
+			providers: []string{"first", "second"},
+		}
+		for i := 0; i < 2; i++ {
+			if _, err := adapter.Transcribe(context.Background(), []byte{1}); err != nil {
+				t.Fatalf("Transcribe() error = %v", err)
+			}
+		}
+		if provider := adapter.provider(); provider != "first+second" {
+			t.Errorf("provider() = %q, want %q", provider, "first+second")
+		}
+	})
+
+	t.Run("transcriber reports provider", func(t *testing.T) {
+		tr, err := NewTranscriber(Config{
+			Provider:  "whisper-cpp",
+			ServerURL: "http://127.0.0.1:8025/inference",
+			Fallbacks: []Config{
+				{Provider: "openai", APIKey: "test-key", Model: "whisper-1"},
+			},
+		})
+		if err != nil {
+			t.Fatalf("NewTranscriber() error = %v", err)
+		}
+		reporter, ok := tr.(ProviderReporter)
+		if !ok {
+			t.Fatalf("transcriber does not implement ProviderReporter")
+		}
+		if provider := reporter.Provider(); provider != "whisper-cpp" {
+			t.Errorf("Provider() = %q, want %q", provider, "whisper-cpp")
+		}
+	})
+}
+
+func TestNewTranscriber_RetriesInChain(t *testing.T) {
+	tr, err := NewTranscriber(Config{
+		Provider:  "whisper-cpp",
+		ServerURL: "http://127.0.0.1:8025/inference",
+		Fallbacks: []Config{
+			{Provider: "openai", APIKey: "test-key", Model: "whisper-1"},
+			{Provider: "groq-transcription", APIKey: "test-key", Model: "whisper-large-v3"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewTranscriber() error = %v", err)
+	}
+	chain, ok := tr.(*SimpleTranscriber).adapter.(*fallbackAdapter)
+	if !ok {
+		t.Fatalf("adapter is not a fallback chain")
+	}
+
+	// Only the last provider waits out rate limits, the others hand over at once
+	want := []bool{true, true, false}
+	for i, adapter := range chain.adapters {
+		retry, ok := adapter.(*retryAdapter)
+		if !ok {
+			t.Fatalf("provider %d is not retried", i)
+		}
+		if retry.hasFallback != want[i] {
+			t.Errorf("provider %d hasFallback = %v, want %v", i, retry.hasFallback, want[i])
+		}
+	}
+}
+
+func TestConfig_UseServer(t *testing.T) {
+	server := NewWhisperServer(WhisperServerConfig{Port: 8025})
+	config := Config{
+		Provider:  "whisper-cpp",
+		ServerURL: server.Config().URL(),
+		Fallbacks: []Config{
+			{Provider: "whisper-cpp", ServerURL: "http://other:8080/inference"},
+			{Provider: "whisper-cpp", ServerURL: server.Config().URL()},
+		},
+	}
+	config.UseServer(server)
+
+	if config.Server != server {
+		t.Errorf("primary: server not set")
+	}
+	if config.Fallbacks[0].Server != nil {
+		t.Errorf("fallback with another URL: server set")
+	}
+	if config.Fallbacks[1].Server != server {
+		t.Errorf("fallback with the managed URL: server not set")
+	}
+}
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want ErrorKind
+	}{
+		{"unauthorized", &HTTPError{StatusCode: 401}, ErrorAuth},
+		{"forbidden", &HTTPError{StatusCode: 403}, ErrorAuth},
+		{"rate limited", &HTTPError{StatusCode: 429}, ErrorRateLimit},
+		{"bad gateway", &HTTPError{StatusCode: 502}, ErrorServer},
+		{"request timeout", &HTTPError{StatusCode: 408}, ErrorNetwork},
+		{"unsupported audio", &HTTPError{StatusCode: 400}, ErrorBadRequest},
+		{"wrapped", fmt.Errorf("chunk 1/2: %w", &HTTPError{StatusCode: 503}), ErrorServer},
+		{"joined", errors.Join(errors.New("other"), &HTTPError{StatusCode: 401}), ErrorAuth},
+		{"connection refused", &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}, ErrorNetwork},
+		{"connection reset", &url.Error{Op: "Post", URL: "https://api.openai.com/v1", Err: syscall.ECONNRESET}, ErrorNetwork},
+		{"read timeout", &url.Error{Op: "Post", URL: "https://api.openai.com/v1", Err: &net.OpError{Op: "read", Err: os.ErrDeadlineExceeded}}, ErrorNetwork},
+		{"untrusted certificate", &url.Error{Op: "Post", URL: "https://localhost:8000/v1", Err: x509.UnknownAuthorityError{}}, ErrorOther},
+		{"unsupported scheme", &url.Error{Op: "Post", URL: "ftp://localhost/v1", Err: errors.New(`unsupported protocol scheme "ftp"`)}, ErrorOther},
+		{"unknown host", &url.Error{Op: "Post", URL: "https://nowhere.invalid/v1", Err: &net.OpError{Op: "dial", Err: &net.DNSError{Err: "no such host", Name: "nowhere.invalid", IsNotFound: true}}}, ErrorOther},
+		{"deadline", context.DeadlineExceeded, ErrorNetwork},
+		{"cancelled", fmt.Errorf("send: %w", context.Canceled), ErrorOther},
+		{"unknown", errors.New("whisper-cli: exit status 1"), ErrorOther},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ClassifyError(tt.err); got != tt.want {
+				t.Errorf("ClassifyError() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 0},
+		{"3", 3 * time.Second},
+		{"-1", 0},
+		{now.Add(10 * time.Second).Format(http.TimeFormat), 10 * time.Second},
+		{now.Add(-time.Minute).Format(http.TimeFormat), 0},
+		{"soon", 0},
+	}
+
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.value, now); got != tt.want {
+			t.Errorf("parseRetryAfter(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestNewHTTPError(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"openai", `{"error": {"message": "Invalid API key", "type": "invalid_request_error"}}`, "Invalid API key"},
+		{"plain error", `{"error": "model not loaded"}`, "model not loaded"},
+		{"message", `{"message": "Unauthorized"}`, "Unauthorized"},
+		{"detail", `{"detail": "Not Found"}`, "Not Found"},
+		{"text", "upstream timed out\n", "upstream timed out"},
+		{"empty", "", "Service Unavailable"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{
+				StatusCode: http.StatusServiceUnavailable,
+				Header:     http.Header{"Retry-After": []string{"2"}},
+				Body:       io.NopCloser(strings.NewReader(tt.body)),
+			}
+			err := newHTTPError(resp)
+			if err.Message != tt.want {
+				t.Errorf("Message = %q, want %q", err.Message, tt.want)
+			}
+			if err.RetryAfter != 2*time.Second {
+				t.Errorf("RetryAfter = %v, want 2s", err.RetryAfter)
+			}
+		})
+	}
+}
+
+func TestRetryAdapter(t *testing.T) {
+	tests := []struct {
+		name        string
+		errs        []error // returned by the attempts before one succeeds
+		hasFallback bool
+		wantCalls   int
+		wantErr     bool
+	}{
+		{"succeeds first time", nil, false, 1, false},
+		{"retries server error", []error{&HTTPError{StatusCode: 502}}, false, 2, false},
+		{"retries network error", []error{&net.OpError{Op: "read", Err: syscall.ECONNRESET}}, false, 2, false},
+		{"retries rate limit", []error{&HTTPError{StatusCode: 429, RetryAfter: time.Millisecond}}, false, 2, false},
+		{"gives up after attempts", []error{&HTTPError{StatusCode: 500}, &HTTPError{StatusCode: 500}, &HTTPError{StatusCode: 500}}, false, 3, true},
+		{"no retry on auth", []error{&HTTPError{StatusCode: 401}}, false, 1, true},
+		{"no retry on bad request", []error{&HTTPError{StatusCode: 400}}, false, 1, true},
+		{"no retry on unknown", []error{errors.New("exit status 1")}, false, 1, true},
+		{"no wait past the budget", []error{&HTTPError{StatusCode: 429, RetryAfter: time.Hour}}, false, 1, true},
+		{"rate limit goes to the fallback", []error{&HTTPError{StatusCode: 429}}, true, 1, true},
+		{"retry-after goes to the fallback", []error{&HTTPError{StatusCode: 503, RetryAfter: time.Millisecond}}, true, 1, true},
+		{"server error retried before the fallback", []error{&HTTPError{StatusCode: 502}}, true, 2, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			adapter := &retryAdapter{
+				adapter: &MockTranscriptionAdapter{
+					TranscribeFunc: func(ctx context.Context, audioData []byte) (string, error) {
+						calls++
+						if calls <= len(tt.errs) {
+							return "", tt.errs[calls-1]
+						}
+						return "hello", nil
+					},
+				},
+				provider:    "test",
+				hasFallback: tt.hasFallback,
+				attempts:    3,
+				baseDelay:   time.Millisecond,
+				maxDelay:    5 * time.Millisecond,
+				budget:      time.Second,
+			}
+
+			text, err := adapter.Transcribe(context.Background(), []byte{1, 2})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Transcribe() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && text != "hello" {
+				t.Errorf("Transcribe() = %q, want %q", text, "hello")
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("got %d calls, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+
+	deadlines := []struct {
+		name    string
+		timeout time.Duration // of the caller's context, 0 for none
+		budget  time.Duration
+	}{
+		{"stops at the caller's deadline", 100 * time.Millisecond, time.Minute},
+		{"stops at the budget without a deadline", 0, 100 * time.Millisecond},
+	}
+	for _, tt := range deadlines {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			adapter := &retryAdapter{
+				adapter: &MockTranscriptionAdapter{
+					TranscribeFunc: func(ctx context.Context, audioData []byte) (string, error) {
+						calls++
+						return "", &HTTPError{StatusCode: 503}
+					},
+				},
+				provider:  "test",
+				attempts:  3,
+				baseDelay: time.Second,
+				maxDelay:  time.Second,
+				budget:    tt.budget,
+			}
+
+			ctx := context.Background()
+			if tt.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.timeout)
+				defer cancel()
+			}
+			start := time.Now()
+			if _, err := adapter.Transcribe(ctx, []byte{1}); err == nil {
+				t.Fatal("Transcribe() succeeded, want an error")
+			}
+			if calls != 1 {
+				t.Errorf("got %d calls, want 1", calls)
+			}
+			if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+				t.Errorf("gave up after %v, want at once", elapsed)
+			}
+		})
+	}
+
+	t.Run("retried requests run under the budget", func(t *testing.T) {
+		var deadlines []bool
+		adapter := &retryAdapter{
+			adapter: &MockTranscriptionAdapter{
+				TranscribeFunc: func(ctx context.Context, audioData []byte) (string, error) {
+					_, ok := ctx.Deadline()
+					deadlines = append(deadlines, ok)
+					if len(deadlines) == 1 {
+						return "", &HTTPError{StatusCode: 502}
+					}
+					return "hello", nil
+				},
+			},
+			provider:  "test",
+			attempts:  3,
+			baseDelay: time.Millisecond,
+			maxDelay:  time.Millisecond,
+			budget:    time.Second,
+		}
+		if _, err := adapter.Transcribe(context.Background(), []byte{1}); err != nil {
+			t.Fatalf("Transcribe() error = %v", err)
+		}
+		if len(deadlines) != 2 || deadlines[0] || !deadlines[1] {
+			t.Errorf("deadline set per attempt = %v, want only on the retry", deadlines)
+		}
+	})
+
+	t.Run("honors retry-after from the server", func(t *testing.T) {
+		var calls atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if calls.Add(1) == 1 {
+				w.Header().Set("Retry-After", "1")
+				w.WriteHeader(http.StatusTooManyRequests)
+				fmt.Fprint(w, `{"error": {"message": "Rate limit reached"}}`)
+				return
+			}
+			fmt.Fprint(w, `{"text": "hello"}`)
+		}))
+		defer server.Close()
+
+		adapter := newRetryAdapter(NewOpenAICompatibleAdapter(Config{
+			Provider:     "openai-compatible",
+			BaseURL:      server.URL + "/v1",
+			SampleRate:   16000,
+			Channels:     1,
+			UploadFormat: FormatWAV,
+		}), "openai-compatible", false)
+
+		start := time.Now()
+		text, err := adapter.Transcribe(context.Background(), make([]byte, 3200))
+		if err != nil {
+			t.Fatalf("Transcribe() error = %v", err)
+		}
+		if text != "hello" {
+			t.Errorf("Transcribe() = %q, want %q", text, "hello")
+		}
+		if elapsed := time.Since(start); elapsed < time.Second {
+			t.Errorf("retried after %v, want at least the 1s Retry-After", elapsed)
+		}
+	})
+}
+
+func TestOpenAICompatibleAdapter(t *testing.T) {
+	tests := []struct {
+		name     string
+		endpoint string
+		wantPath string
+	}{
+		{"default endpoint", "", "/v1/audio/transcriptions"},
+		{"transcription", EndpointTranscription, "/v1/audio/transcriptions"},
+		{"translation", EndpointTranslation, "/v1/audio/translations"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var path, model, auth, custom string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				path = r.URL.Path
+				model = r.FormValue("model")
+				auth = r.Header.Get("Authorization")
+				custom = r.Header.Get("X-Api-Key")
+				fmt.Fprint(w, `{"text": "hello"}`)
+			}))
+			defer server.Close()
+
+			adapter := NewOpenAICompatibleAdapter(Config{
+				Provider:     "openai-compatible",
+				BaseURL:      server.URL + "/v1",
+				Headers:      map[string]string{"X-Api-Key": "secret"},
+				Endpoint:     tt.endpoint,
+				Model:        "my-local-whisper",
+				SampleRate:   16000,
+				Channels:     1,
+				UploadFormat: FormatWAV,
+			})
+			text, err := adapter.Transcribe(context.Background(), make([]byte, 3200))
+			if err != nil {
+				t.Fatalf("Transcribe() error = %v", err)
+			}
+			if text != "hello" {
+				t.Errorf("Transcribe() = %q, want %q", text, "hello")
+			}
+			if path != tt.wantPath {
+				t.Errorf("request path = %q, want %q", path, tt.wantPath)
+			}
+			if model != "my-local-whisper" {
+				t.Errorf("model = %q, want %q", model, "my-local-whisper")
+			}
+			if custom != "secret" {
+				t.Errorf("X-Api-Key header = %q, want %q", custom, "secret")
+			}
+			if auth != "" {
+				t.Errorf("Authorization header = %q, want none without an API key", auth)
+			}
+		})
+	}
+}
+
+func TestOpenAICompatibleAdapter_Presets(t *testing.T) {
+	tests := []struct {
+		provider     string
+		wantBaseURL  string
+		wantEndpoint string
+		wantName     string
+	}{
+		{"openai", "https://api.openai.com/v1", EndpointTranscription, "openai"},
+		{"groq-transcription", "https://api.groq.com/openai/v1", EndpointTranscription, "groq"},
+		{"groq-translation", "https://api.groq.com/openai/v1", EndpointTranslation, "groq"},
+		{"mistral-transcription", "https://api.mistral.ai/v1", EndpointTranscription, "mistral"},
+		{"openai-compatible", "http://localhost:8000/v1", EndpointTranslation, "openai-compatible"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.provider, func(t *testing.T) {
+			// Settings meant for openai-compatible must not leak into a preset
+			adapter := NewOpenAICompatibleAdapter(Config{
+				Provider: tt.provider,
+				APIKey:   "key",
+				BaseURL:  "http://localhost:8000/v1",
+				Endpoint: EndpointTranslation,
+				Headers:  map[string]string{"X-Api-Key": "secret"},
+			})
+			if adapter.config.BaseURL != tt.wantBaseURL {
+				t.Errorf("BaseURL = %q, want %q", adapter.config.BaseURL, tt.wantBaseURL)
+			}
+			if adapter.endpoint() != tt.wantEndpoint {
+				t.Errorf("endpoint() = %q, want %q", adapter.endpoint(), tt.wantEndpoint)
+			}
+			if adapter.name != tt.wantName {
+				t.Errorf("name = %q, want %q", adapter.name, tt.wantName)
+			}
+			if _, ok := openAIPresets[tt.provider]; ok && adapter.config.Headers != nil {
+				t.Errorf("Headers = %v, want none for a preset", adapter.config.Headers)
+			}
+		})
+	}
+}
+
+func TestWhisperCLIAdapter(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	// A stand-in for whisper-cli that records its arguments and writes the
+	// JSON file next to the --output-file prefix
+	dir := t.TempDir()
+	argsFile := filepath.Join(dir, "args")
+	binary := filepath.Join(dir, "whisper-cli")
+	script := `#!/bin/sh
+printf '%s\n' "$@" > ` + argsFile + `
+while [ $# -gt 0 ]; do
+	if [ "$1" = "--output-file" ]; then out="$2"; fi
+	shift
+done
+printf '{"transcription": [{"text": " Hello"}, {"text": " world."}]}' > "$out.json"
+`
+	if err := os.WriteFile(binary, []byte(script), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		config   Config
+		prompt   string
+		wantArgs []string
+		noArgs   []string
+	}{
+		{
+			name:     "auto-detected language",
+			config:   Config{ModelPath: "/models/ggml-base.bin"},
+			wantArgs: []string{"--model\n/models/ggml-base.bin", "--language\nauto", "--output-json"},
+			noArgs:   []string{"--threads", "--translate", "--prompt"},
+		},
+		{
+			name:     "language, threads, translate and prompt",
+			config:   Config{ModelPath: "/models/ggml-small.bin", Language: "it", Threads: 6, Translate: true},
+			prompt:   "Ciao",
+			wantArgs: []string{"--language\nit", "--threads\n6", "--translate", "--prompt\nCiao"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := tt.config
+			config.Provider = "whisper-cli"
+			config.WhisperCLI = binary
+			config.SampleRate, config.Channels = 16000, 1
+
+			text, err := NewWhisperCLIAdapter(config).TranscribeWithPrompt(context.Background(), make([]byte, 3200), tt.prompt)
+			if err != nil {
+				t.Fatalf("TranscribeWithPrompt() error = %v", err)
+			}
+			if text != "Hello world." {
+				t.Errorf("TranscribeWithPrompt() = %q, want %q", text, "Hello world.")
+			}
+
+			data, err := os.ReadFile(argsFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			args := string(data)
+			for _, want := range tt.wantArgs {
+				if !strings.Contains(args, want+"\n") {
+					t.Errorf("arguments %q do not contain %q", args, want)
+				}
+			}
+			for _, unwanted := range tt.noArgs {
+				if strings.Contains(args, unwanted) {
+					t.Errorf("arguments %q contain %q", args, unwanted)
+				}
+			}
+		})
+	}
+
+	missing := NewWhisperCLIAdapter(Config{WhisperCLI: filepath.Join(dir, "missing"), SampleRate: 16000, Channels: 1})
+	if _, err := missing.Transcribe(context.Background(), make([]byte, 3200)); err == nil {
+		t.Errorf("Transcribe() expected an error for a missing binary")
+	}
+}
+
+// freePort returns a local port nothing listens on
+func freePort(t *testing.T) int {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	return ln.Addr().(*net.TCPAddr).Port
+}
+
+func waitForState(t *testing.T, server *WhisperServer, want string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for server.State() != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("whisper-server state = %q, want %q", server.State(), want)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestWhisperServer(t *testing.T) {
+	t.Setenv("HYPRVOICE_FAKE_WHISPER_SERVER", "1")
+	self, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("starts on demand and stops when idle", func(t *testing.T) {
+		serverConfig := WhisperServerConfig{Binary: self, ModelPath: "ggml-test.bin", Port: freePort(t), IdleTimeout: 100 * time.Millisecond}
+		server := NewWhisperServer(serverConfig)
+		defer server.Stop()
+
+		if state := server.State(); state != ServerStopped {
+			t.Fatalf("State() = %q before first use, want %q", state, ServerStopped)
+		}
+
+		adapter := NewWhisperCppAdapter(Config{ServerURL: serverConfig.URL(), Server: server, SampleRate: 16000, Channels: 1})
+		text, err := adapter.Transcribe(context.Background(), make([]byte, 3200))
+		if err != nil {
+			t.Fatalf("Transcribe() error = %v", err)
+		}
+		if text != "from the managed server" {
+			t.Errorf("Transcribe() = %q", text)
+		}
+
+		waitForState(t, server, ServerStopped)
+
+		// The next use starts it again
+		release, err := server.Acquire(context.Background())
+		if err != nil {
+			t.Fatalf("Acquire() after idle stop error = %v", err)
+		}
+		if state := server.State(); state != ServerReady {
+			t.Errorf("State() = %q after Acquire, want %q", state, ServerReady)
+		}
+		release()
+	})
+
+	t.Run("restarts after a crash", func(t *testing.T) {
+		server := NewWhisperServer(WhisperServerConfig{Binary: self, ModelPath: "ggml-test.bin", Port: freePort(t)})
+		defer server.Stop()
+
+		release, err := server.Acquire(context.Background())
+		if err != nil {
+			t.Fatalf("Acquire() error = %v", err)
+		}
+		release()
+
+		server.mu.Lock()
+		first := server.cmd.Process
+		server.mu.Unlock()
+		if err := first.Kill(); err != nil {
+			t.Fatal(err)
+		}
+
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			server.mu.Lock()
+			restarted := server.cmd != nil && server.cmd.Process != first && server.state == ServerReady
+			server.mu.Unlock()
+			if restarted {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("whisper-server was not restarted, state = %q", server.State())
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	})
+
+	t.Run("uses a server already on the port", func(t *testing.T) {
+		external := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"status": "ok"}`)
+		}))
+		defer external.Close()
+
+		port := external.Listener.Addr().(*net.TCPAddr).Port
+		server := NewWhisperServer(WhisperServerConfig{Binary: filepath.Join(t.TempDir(), "missing"), Port: port})
+		defer server.Stop()
+
+		release, err := server.Acquire(context.Background())
+		if err != nil {
+			t.Fatalf("Acquire() error = %v", err)
+		}
+		release()
+		if state := server.State(); state != ServerExternal {
+			t.Errorf("State() = %q, want %q", state, ServerExternal)
+		}
+	})
+
+	t.Run("probing the port does not hold the lock", func(t *testing.T) {
+		probing := make(chan struct{})
+		unblock := make(chan struct{})
+		slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(probing)
+			<-unblock
+		}))
+		defer slow.Close()
+		defer close(unblock)
+
+		port := slow.Listener.Addr().(*net.TCPAddr).Port
+		server := NewWhisperServer(WhisperServerConfig{Binary: filepath.Join(t.TempDir(), "missing"), Port: port})
+		defer server.Stop()
+
+		go server.Acquire(context.Background())
+		<-probing
+
+		states := make(chan string, 1)
+		go func() { states <- server.State() }()
+		select {
+		case <-states:
+		case <-time.After(500 * time.Millisecond):
+			t.Fatal("State() blocked while Acquire probed the port")
+		}
+	})
+
+	t.Run("missing binary", func(t *testing.T) {
+		server := NewWhisperServer(WhisperServerConfig{Binary: filepath.Join(t.TempDir(), "missing"), Port: freePort(t)})
+		defer server.Stop()
+
+		if _, err := server.Acquire(context.Background()); err == nil {
+			t.Errorf("Acquire() expected an error for a missing binary")
+		}
+		if state := server.State(); state != ServerFailed {
+			t.Errorf("State() = %q, want %q", state, ServerFailed)
+		}
+	})
+}
+
+type audioSegment struct {
+	duration time.Duration
+	speech   bool
+}
+
+// testAudio builds 16 kHz mono s16 audio from segments of silence and tone
+func testAudio(segments ...audioSegment) []byte {
+	var audio []byte
+	for _, seg := range segments {
+		samples := int(seg.duration.Seconds() * 16000)
+		for i := 0; i < samples; i++ {
+			var v int16
+			if seg.speech {
+				v = int16(8000 * math.Sin(2*math.Pi*440*float64(i)/16000))
+			}
+			audio = binary.LittleEndian.AppendUint16(audio, uint16(v))
+		}
+	}
+	return audio
+}
+
+func TestTrimSilence(t *testing.T) {
+	config := TrimConfig{Enabled: true, Threshold: 0.01, MaxPause: time.Second}
+	const bytesPerSecond = 16000 * 2
+
+	tests := []struct {
+		name     string
+		audio    []byte
+		expected time.Duration
+	}{
+		{
+			name:     "leading and trailing silence",
+			audio:    testAudio(audioSegment{2 * time.Second, false}, audioSegment{time.Second, true}, audioSegment{3 * time.Second, false}),
+			expected: time.Second + 2*trimPadding,
+		},
+		{
+			name:     "long pause shortened",
+			audio:    testAudio(audioSegment{time.Second, true}, audioSegment{5 * time.Second, false}, audioSegment{time.Second, true}),
+			expected: 3 * time.Second,
+		},
+		{
+			name:     "short pause kept",
+			audio:    testAudio(audioSegment{time.Second, true}, audioSegment{500 * time.Millisecond, false}, audioSegment{time.Second, true}),
+			expected: 2500 * time.Millisecond,
+		},
+		{
+			name:     "silence only is left untouched",
+			audio:    testAudio(audioSegment{2 * time.Second, false}),
+			expected: 2 * time.Second,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trimmed := trimSilence(tt.audio, 16000, 1, config)
+			got := time.Duration(len(trimmed)) * time.Second / bytesPerSecond
+			if diff := got - tt.expected; diff < -trimWindow || diff > trimWindow {
+				t.Errorf("trimSilence() kept %v, want %v", got, tt.expected)
+			}
+			if len(trimmed)%2 != 0 {
+				t.Errorf("trimSilence() returned %d bytes, not sample aligned", len(trimmed))
+			}
+		})
+	}
+}
+
+func TestSimpleTranscriber_TranscribeAllTrims(t *testing.T) {
+	var uploaded int
+	adapter := &MockTranscriptionAdapter{
+		TranscribeFunc: func(ctx context.Context, audioData []byte) (string, error) {
+			uploaded = len(audioData)
+			return "hello", nil
+		},
+	}
+	config := Config{
+		Provider:   "openai",
+		SampleRate: 16000,
+		Channels:   1,
+		Trim:       TrimConfig{Enabled: true, Threshold: 0.01, MaxPause: time.Second},
+	}
+	transcriber := NewSimpleTranscriber(config, adapter)
+	transcriber.audioBuffer = testAudio(audioSegment{3 * time.Second, false}, audioSegment{time.Second, true})
+
+	if err := transcriber.transcribeAll(context.Background()); err != nil {
+		t.Fatalf("transcribeAll() error = %v", err)
+	}
+	if uploaded == 0 || uploaded >= len(transcriber.audioBuffer) {
+		t.Errorf("uploaded %d bytes, want trimmed audio shorter than %d", uploaded, len(transcriber.audioBuffer))
+	}
+}
+
+func TestConvertToWAV(t *testing.T) {
+	tests := []struct {
+		name       string
+		audio      []byte
+		sampleRate int
+		channels   int
+		wantErr    bool
+	}{
+		{name: "mono 16k", audio: make([]byte, 320), sampleRate: 16000, channels: 1},
+		{name: "stereo 48k", audio: make([]byte, 384), sampleRate: 48000, channels: 2},
+		{name: "partial stereo sample", audio: make([]byte, 6), sampleRate: 48000, channels: 2, wantErr: true},
+		{name: "missing sample rate", audio: make([]byte, 320), sampleRate: 0, channels: 1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wav, err := convertToWAV(tt.audio, tt.sampleRate, tt.channels)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("convertToWAV() expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("convertToWAV() error = %v", err)
+			}
+			if got := binary.LittleEndian.Uint16(wav[22:]); int(got) != tt.channels {
+				t.Errorf("channels = %d, want %d", got, tt.channels)
+			}
+			if got := binary.LittleEndian.Uint32(wav[24:]); int(got) != tt.sampleRate {
+				t.Errorf("sample rate = %d, want %d", got, tt.sampleRate)
+			}
+		})
+	}
+}
+
+func TestUploadFormat(t *testing.T) {
+	tests := []struct {
+		provider  string
+		requested string
+		expected  string
+	}{
+		{"openai", "", FormatWAV},
+		{"openai", FormatFLAC, FormatFLAC},
+		{"openai-compatible", "", FormatWAV},
+		{"groq-transcription", FormatOpus, FormatOpus},
+		{"mistral-transcription", FormatWAV, FormatWAV},
+		{"whisper-cpp", "", FormatWAV},
+		{"whisper-cpp", FormatFLAC, FormatWAV},
+		{"whisper-cli", FormatOpus, FormatWAV},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.provider+"/"+tt.requested, func(t *testing.T) {
+			if got := UploadFormat(tt.provider, tt.requested); got != tt.expected {
+				t.Errorf("UploadFormat() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestEncodeUpload(t *testing.T) {
+	audio := testAudio(audioSegment{time.Second, true})
+
+	tests := []struct {
+		format    string
+		filenames []string // opus falls back to FLAC without ffmpeg
+		magic     []string
+	}{
+		{FormatWAV, []string{"audio.wav"}, []string{"RIFF"}},
+		{FormatFLAC, []string{"audio.flac"}, []string{"fLaC"}},
+		{FormatOpus, []string{"audio.ogg", "audio.flac"}, []string{"OggS", "fLaC"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			config := Config{SampleRate: 16000, Channels: 1, UploadFormat: tt.format}
+			data, filename, err := encodeUpload(context.Background(), audio, config)
+			if err != nil {
+				t.Fatalf("encodeUpload() error = %v", err)
+			}
+
+			matched := false
+			for i := range tt.filenames {
+				if filename == tt.filenames[i] && string(data[:4]) == tt.magic[i] {
+					matched = true
+				}
+			}
+			if !matched {
+				t.Errorf("encodeUpload() = %s starting with %q", filename, data[:4])
+			}
+			if tt.format != FormatWAV && len(data) >= len(audio) {
+				t.Errorf("encodeUpload() did not compress: %d bytes from %d", len(data), len(audio))
+			}
+		})
+	}
+}
+
+// promptRecordingAdapter records the prompt each chunk was transcribed with
+type promptRecordingAdapter struct {
+	mu      sync.Mutex
+	prompts []string
+}
+
+func (a *promptRecordingAdapter) Transcribe(ctx context.Context, audioData []byte) (string, error) {
+	return a.TranscribeWithPrompt(ctx, audioData, "")
+}
+
+func (a *promptRecordingAdapter) TranscribeWithPrompt(ctx context.Context, audioData []byte, prompt string) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.prompts = append(a.prompts, prompt)
+	return fmt.Sprintf("part%d", len(a.prompts)), nil
+}
+
+func TestSplitAudio(t *testing.T) {
+	speech := audioSegment{2 * time.Second, true}
+	pause := audioSegment{time.Second, false}
+	audio := testAudio(speech, pause, speech, pause, speech)
+	const maxBytes = 110000
+
+	chunks := splitAudio(audio, 16000, 1, maxBytes)
+	if len(chunks) != 3 {
+		t.Fatalf("splitAudio() returned %d chunks, want 3", len(chunks))
+	}
+
+	var joined []byte
+	for i, chunk := range chunks {
+		if len(chunk) > maxBytes {
+			t.Errorf("chunk %d has %d bytes, over the %d limit", i, len(chunk), maxBytes)
+		}
+		if len(chunk)%2 != 0 {
+			t.Errorf("chunk %d is not sample aligned", i)
+		}
+		if i > 0 {
+			// Cuts must land in a pause, never inside speech
+			if level := recording.ComputeLevel(chunk[:640]).RMS; level > 0.001 {
+				t.Errorf("chunk %d starts inside speech (level %.3f)", i, level)
+			}
+		}
+		joined = append(joined, chunk...)
+	}
+	if string(joined) != string(audio) {
+		t.Errorf("chunks do not add up to the original audio")
+	}
+
+	if got := splitAudio(audio[:1000], 16000, 1, maxBytes); len(got) != 1 {
+		t.Errorf("short audio split into %d chunks, want 1", len(got))
+	}
+}
+
+func TestPromptTail(t *testing.T) {
+	if got := promptTail("  short text "); got != "short text" {
+		t.Errorf("promptTail() = %q, want %q", got, "short text")
+	}
+
+	long := strings.Repeat("word ", 500) + "end"
+	got := promptTail(long)
+	if len(got) > maxPromptChars || !strings.HasSuffix(got, "end") || !strings.HasPrefix(got, "word") {
+		t.Errorf("promptTail() = %q (%d chars), want whole words ending the text", got, len(got))
+	}
+}
+
+func TestSimpleTranscriber_TranscribeChunks(t *testing.T) {
+	speech := audioSegment{2 * time.Second, true}
+	pause := audioSegment{time.Second, false}
+	audio := testAudio(speech, pause, speech, pause, speech)
+
+	t.Run("sequential chunks pass the previous text as prompt", func(t *testing.T) {
+		adapter := &promptRecordingAdapter{}
+		config := Config{SampleRate: 16000, Channels: 1, MaxUploadSize: 110000, ChunkConcurrency: 1}
+		text, err := NewSimpleTranscriber(config, adapter).transcribe(context.Background(), audio)
+		if err != nil {
+			t.Fatalf("transcribe() error = %v", err)
+		}
+		if text != "part1 part2 part3" {
+			t.Errorf("transcribe() = %q, want %q", text, "part1 part2 part3")
+		}
+		want := []string{"", "part1", "part2"}
+		if fmt.Sprint(adapter.prompts) != fmt.Sprint(want) {
+			t.Errorf("prompts = %q, want %q", adapter.prompts, want)
+		}
+	})
+
+	t.Run("parallel chunks keep their order", func(t *testing.T) {
+		var calls atomic.Int32
+		adapter := &MockTranscriptionAdapter{
+			TranscribeFunc: func(ctx context.Context, audioData []byte) (string, error) {
+				calls.Add(1)
+				// Later chunks are shorter and finish first
+				time.Sleep(time.Duration(len(audioData)/1000) * time.Millisecond)
+				return fmt.Sprintf("%d", len(audioData)), nil
+			},
+		}
+		config := Config{SampleRate: 16000, Channels: 1, MaxUploadSize: 110000, ChunkConcurrency: 3}
+		text, err := NewSimpleTranscriber(config, adapter).transcribe(context.Background(), audio)
+		if err != nil {
+			t.Fatalf("transcribe() error = %v", err)
+		}
+
+		chunks := splitAudio(audio, 16000, 1, 110000-1100)
+		var want []string
+		for _, chunk := range chunks {
+			want = append(want, fmt.Sprintf("%d", len(chunk)))
+		}
+		if text != strings.Join(want, " ") {
+			t.Errorf("transcribe() = %q, want %q", text, strings.Join(want, " "))
+		}
+		if calls.Load() != 3 {
+			t.Errorf("adapter called %d times, want 3", calls.Load())
+		}
+	})
+
+	t.Run("a failing chunk fails the transcription", func(t *testing.T) {
+		var calls atomic.Int32
+		adapter := &MockTranscriptionAdapter{
+			TranscribeFunc: func(ctx context.Context, audioData []byte) (string, error) {
+				if calls.Add(1) == 2 {
+					return "", fmt.Errorf("upload failed")
+				}
+				return "ok", nil
+			},
+		}
+		config := Config{SampleRate: 16000, Channels: 1, MaxUploadSize: 110000, ChunkConcurrency: 1}
+		_, err := NewSimpleTranscriber(config, adapter).transcribe(context.Background(), audio)
+		if err == nil || !strings.Contains(err.Error(), "chunk 2/3") {
+			t.Errorf("transcribe() error = %v, want chunk 2/3 failure", err)
+		}
+	})
+}
+
+func TestTranscribeFile(t *testing.T) {
+	var uploaded int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		data, _ := io.ReadAll(file)
+		uploaded = len(data)
+		fmt.Fprint(w, `{"text": "hello from a file"}`)
+	}))
+	defer server.Close()
+
+	// One second of 48 kHz stereo is uploaded as 16 kHz mono
+	pcm := make([]byte, 48000*2*2)
+	for i := 0; i < len(pcm); i += 4 {
+		v := uint16(int16(8000 * math.Sin(2*math.Pi*440*float64(i/4)/48000)))
+		binary.LittleEndian.PutUint16(pcm[i:], v)
+		binary.LittleEndian.PutUint16(pcm[i+2:], v)
+	}
+	path := filepath.Join(t.TempDir(), "memo.wav")
+	if err := os.WriteFile(path, recording.EncodeWAV(pcm, 48000, 2, "s16"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	config := Config{Provider: "whisper-cpp", ServerURL: server.URL}
+	text, err := TranscribeFile(context.Background(), config, path)
+	if err != nil {
+		t.Fatalf("TranscribeFile() error = %v", err)
+	}
+	if text != "hello from a file" {
+		t.Errorf("TranscribeFile() = %q", text)
+	}
+	if want := 44 + 16000*2; uploaded < want-64 || uploaded > want+64 {
+		t.Errorf("uploaded %d bytes, want about %d", uploaded, want)
+	}
+
+	if _, err := TranscribeFile(context.Background(), config, filepath.Join(t.TempDir(), "missing.wav")); err == nil {
+		t.Errorf("TranscribeFile() expected an error for a missing file")
+	}
+}
+
+func TestSegmenter(t *testing.T) {
+	speech := func(d time.Duration) audioSegment { return audioSegment{d, true} }
+	pause := func(d time.Duration) audioSegment { return audioSegment{d, false} }
+
+	write := func(s *segmenter, audio []byte) [][]byte {
+		var segments [][]byte
+		for i := 0; i < len(audio); i += 3200 { // 100ms frames
+			segments = append(segments, s.write(audio[i:min(i+3200, len(audio))])...)
+		}
+		return segments
+	}
+
+	t.Run("cuts in pauses once the segment is long enough", func(t *testing.T) {
+		audio := testAudio(speech(4*time.Second), pause(time.Second), speech(time.Second), pause(time.Second),
+			speech(4*time.Second), pause(2*time.Second))
+		s := newSegmenter(Config{SampleRate: 16000, Channels: 1})
+		segments := write(s, audio)
+
+		// The short pause after one second of speech is not a cut
+		if len(segments) != 2 {
+			t.Fatalf("got %d segments, want 2", len(segments))
+		}
+		var joined []byte
+		for i, seg := range segments {
+			if level := recording.ComputeLevel(seg[len(seg)-640:]).RMS; level > 0.001 {
+				t.Errorf("segment %d ends inside speech (level %.3f)", i, level)
+			}
+			joined = append(joined, seg...)
+		}
+		tail := s.flush()
+		if hasSpeech(tail, s.windowBytes, s.threshold) {
+			t.Errorf("tail after the last pause should be silence")
+		}
+		if string(append(joined, tail...)) != string(audio) {
+			t.Errorf("segments do not add up to the original audio")
+		}
+	})
+
+	t.Run("cuts long speech without pauses", func(t *testing.T) {
+		s := newSegmenter(Config{SampleRate: 16000, Channels: 1})
+		segments := write(s, testAudio(speech(40*time.Second)))
+		if len(segments) != 1 {
+			t.Fatalf("got %d segments, want 1", len(segments))
+		}
+		if d := len(segments[0]) / 32000; d > 30 || d < 15 {
+			t.Errorf("forced segment is %ds long, want between 15s and 30s", d)
+		}
+	})
+
+	t.Run("silence never ends a segment", func(t *testing.T) {
+		s := newSegmenter(Config{SampleRate: 16000, Channels: 1, StreamingPause: 500 * time.Millisecond})
+		if segments := write(s, testAudio(pause(10*time.Second))); len(segments) != 0 {
+			t.Errorf("got %d segments from silence, want 0", len(segments))
+		}
+	})
+}
+
+func TestStreamingTranscriber(t *testing.T) {
+	speech := audioSegment{4 * time.Second, true}
+	pause := audioSegment{time.Second, false}
+	config := Config{SampleRate: 16000, Channels: 1, Streaming: true}
+
+	feed := func(frameCh chan<- recording.AudioFrame, audio []byte) {
+		for i := 0; i < len(audio); i += 3200 {
+			frameCh <- recording.AudioFrame{Data: audio[i:min(i+3200, len(audio))]}
+		}
+	}
+
+	t.Run("transcribes segments before stop", func(t *testing.T) {
+		adapter := &promptRecordingAdapter{}
+		st := NewStreamingTranscriber(config, adapter)
+		frameCh := make(chan recording.AudioFrame, 10)
+		if _, err := st.Start(context.Background(), frameCh); err != nil {
+			t.Fatalf("Start() error = %v", err)
+		}
+
+		feed(frameCh, testAudio(speech, pause, speech, pause))
+		deadline := time.Now().Add(2 * time.Second)
+		for {
+			adapter.mu.Lock()
+			calls := len(adapter.prompts)
+			adapter.mu.Unlock()
+			if calls == 2 {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("%d segments transcribed while recording, want 2", calls)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+
+		// Only the tail is left at stop
+		feed(frameCh, testAudio(speech))
+		close(frameCh)
+		if err := st.Stop(context.Background()); err != nil {
+			t.Fatalf("Stop() error = %v", err)
+		}
+
+		text, _ := st.GetFinalTranscription()
+		if text != "part1 part2 part3" {
+			t.Errorf("transcription = %q, want %q", text, "part1 part2 part3")
+		}
+		if want := []string{"", "part1", "part2"}; fmt.Sprint(adapter.prompts) != fmt.Sprint(want) {
+			t.Errorf("prompts = %q, want %q", adapter.prompts, want)
+		}
+	})
+
+	t.Run("retries failed segments at stop", func(t *testing.T) {
+		var calls atomic.Int32
+		adapter := &MockTranscriptionAdapter{
+			TranscribeFunc: func(ctx context.Context, audioData []byte) (string, error) {
+				if calls.Add(1) == 1 {
+					return "", fmt.Errorf("connection reset")
+				}
+				return fmt.Sprintf("call%d", calls.Load()), nil
+			},
+		}
+		st := NewStreamingTranscriber(config, adapter)
+		var emitted []string
+		st.OnSegment(func(text string) { emitted = append(emitted, text) })
+		frameCh := make(chan recording.AudioFrame, 10)
+		if _, err := st.Start(context.Background(), frameCh); err != nil {
+			t.Fatalf("Start() error = %v", err)
+		}
+		feed(frameCh, testAudio(speech, pause, speech))
+		close(frameCh)
+
+		if err := st.Stop(context.Background()); err != nil {
+			t.Fatalf("Stop() error = %v", err)
+		}
+		text, _ := st.GetFinalTranscription()
+		if text != "call3 call2" {
+			t.Errorf("transcription = %q, want %q", text, "call3 call2")
+		}
+		// The segment after the failed one is held back until the retry succeeds
+		if want := "[call3 call2]"; fmt.Sprint(emitted) != want {
+			t.Errorf("segments emitted as %q, want %s", emitted, want)
+		}
+	})
+}
@@ -0,0 +1,82 @@
+package transcriber
+
+import (
+	"time"
+
+	"github.com/leonardotrapani/hyprvoice/internal/recording"
+)
+
+// TrimConfig controls silence removal before audio is uploaded
+type TrimConfig struct {
+	Enabled   bool
+	Threshold float64       // RMS level (0.0-1.0) below which audio counts as silence
+	MaxPause  time.Duration // Longest pause kept between words; longer pauses are shortened to this
+}
+
+const (
+	trimWindow  = 20 * time.Millisecond  // resolution of the speech/silence decision
+	trimPadding = 200 * time.Millisecond // silence kept around speech so word edges are not clipped
+)
+
+// trimSilence removes leading and trailing silence from s16 audio and shortens
+// internal pauses to config.MaxPause. Audio without any window above the
+// threshold is returned untouched rather than discarded.
+func trimSilence(audio []byte, sampleRate, channels int, config TrimConfig) []byte {
+	windowBytes := int(trimWindow.Seconds()*float64(sampleRate)) * channels * 2
+	if windowBytes <= 0 || len(audio) < windowBytes {
+		return audio
+	}
+
+	windows := (len(audio) + windowBytes - 1) / windowBytes
+	speech := make([]bool, windows)
+	first, last := -1, -1
+	for i := range speech {
+		end := min((i+1)*windowBytes, len(audio))
+		if recording.ComputeLevel(audio[i*windowBytes:end]).RMS >= config.Threshold {
+			speech[i] = true
+			if first < 0 {
+				first = i
+			}
+			last = i
+		}
+	}
+	if first < 0 {
+		return audio
+	}
+
+	padding := int(trimPadding / trimWindow)
+	// A pause is never cut below the padding on both of its sides
+	maxPause := max(int(config.MaxPause/trimWindow), 2*padding)
+
+	start := max(first-padding, 0)
+	end := min(last+padding+1, windows)
+
+	out := make([]byte, 0, len(audio))
+	for i := start; i < end; {
+		if speech[i] {
+			out = append(out, window(audio, i, windowBytes)...)
+			i++
+			continue
+		}
+
+		// Keep the edges of a long pause and drop its middle
+		runEnd := i
+		for runEnd < end && !speech[runEnd] {
+			runEnd++
+		}
+		keep := min(runEnd-i, maxPause)
+		for j := i; j < i+keep/2; j++ {
+			out = append(out, window(audio, j, windowBytes)...)
+		}
+		for j := runEnd - (keep - keep/2); j < runEnd; j++ {
+			out = append(out, window(audio, j, windowBytes)...)
+		}
+		i = runEnd
+	}
+
+	return out
+}
+
+func window(audio []byte, i, windowBytes int) []byte {
+	return audio[i*windowBytes : min((i+1)*windowBytes, len(audio))]
+}
@@ -0,0 +1,369 @@
+package transcriber
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os/exec"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	// DefaultWhisperServer is the whisper.cpp server binary used when none is configured
+	DefaultWhisperServer = "whisper-server"
+
+	// whisperServerStartTimeout bounds how long loading the model may take
+	whisperServerStartTimeout = 2 * time.Minute
+	// whisperServerPollInterval is how often readiness is checked while starting
+	whisperServerPollInterval = 100 * time.Millisecond
+	// whisperServerStopTimeout is how long the server gets to exit before it is killed
+	whisperServerStopTimeout = 5 * time.Second
+	// whisperServerMaxRestarts limits restarts after crashes until the server is used again
+	whisperServerMaxRestarts = 3
+)
+
+// Managed whisper.cpp server states, as shown by the daemon status
+const (
+	ServerStopped  = "stopped"
+	ServerStarting = "starting"
+	ServerReady    = "ready"
+	ServerExternal = "external" // another process already serves the port
+	ServerFailed   = "failed"
+)
+
+// WhisperServerConfig describes the whisper.cpp server the daemon runs
+type WhisperServerConfig struct {
+	Binary      string // empty uses DefaultWhisperServer
+	ModelPath   string
+	Port        int
+	Threads     int           // 0 uses whisper.cpp's default
+	IdleTimeout time.Duration // unused time before the server is stopped; 0 keeps it running
+}
+
+// URL returns the inference endpoint of the server
+func (c WhisperServerConfig) URL() string {
+	return fmt.Sprintf("http://127.0.0.1:%d/inference", c.Port)
+}
+
+// WhisperServer runs whisper-server on demand and shares it between
+// transcriptions. It is started by the first Acquire, stopped after being
+// unused for the idle timeout and restarted when it crashes.
+type WhisperServer struct {
+	config WhisperServerConfig
+	client *http.Client
+
+	mu       sync.Mutex
+	state    string
+	lastErr  error
+	cmd      *exec.Cmd
+	ready    chan struct{} // closed once the current launch is ready or failed
+	exited   chan struct{} // closed once the current process has exited
+	users    int
+	idle     *time.Timer
+	restarts int
+	closed   bool
+}
+
+func NewWhisperServer(config WhisperServerConfig) *WhisperServer {
+	if config.Binary == "" {
+		config.Binary = DefaultWhisperServer
+	}
+	return &WhisperServer{
+		config: config,
+		client: &http.Client{Timeout: time.Second},
+		state:  ServerStopped,
+	}
+}
+
+// Config returns the configuration the server was created with
+func (s *WhisperServer) Config() WhisperServerConfig {
+	return s.config
+}
+
+// State returns the server state
+func (s *WhisperServer) State() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state
+}
+
+// Acquire starts the server if needed and waits until it accepts requests.
+// The server is kept running until release is called.
+func (s *WhisperServer) Acquire(ctx context.Context) (release func(), err error) {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("whisper-server is shut down")
+	}
+	s.users++
+	if s.idle != nil {
+		s.idle.Stop()
+		s.idle = nil
+	}
+	probe := s.cmd == nil
+	s.mu.Unlock()
+
+	// A server left running elsewhere on the port is used as it is. The probe
+	// runs without the lock so State and release are not held up by it.
+	external := probe && s.healthy(ctx)
+
+	s.mu.Lock()
+	if s.closed {
+		s.users--
+		s.mu.Unlock()
+		return nil, fmt.Errorf("whisper-server is shut down")
+	}
+	// Another Acquire may have launched the server while this one probed
+	if s.cmd == nil {
+		if external {
+			if s.state != ServerExternal {
+				log.Printf("whisper-server: port %d is already served by another process, using it", s.config.Port)
+			}
+			s.state = ServerExternal
+			s.mu.Unlock()
+			return s.releaseFunc(), nil
+		}
+		s.restarts = 0
+		s.launch()
+	}
+	ready := s.ready
+	s.mu.Unlock()
+
+	release = s.releaseFunc()
+	select {
+	case <-ready:
+	case <-ctx.Done():
+		release()
+		return nil, ctx.Err()
+	}
+
+	s.mu.Lock()
+	state, lastErr := s.state, s.lastErr
+	s.mu.Unlock()
+	if state != ServerReady {
+		release()
+		if lastErr == nil {
+			lastErr = fmt.Errorf("whisper-server %s", state)
+		}
+		return nil, lastErr
+	}
+	return release, nil
+}
+
+func (s *WhisperServer) releaseFunc() func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			s.users--
+			if s.users == 0 && s.cmd != nil && s.config.IdleTimeout > 0 {
+				s.idle = time.AfterFunc(s.config.IdleTimeout, s.stopIdle)
+			}
+		})
+	}
+}
+
+// Stop shuts the server down and waits for it to exit
+func (s *WhisperServer) Stop() {
+	s.mu.Lock()
+	s.closed = true
+	if s.idle != nil {
+		s.idle.Stop()
+		s.idle = nil
+	}
+	exited := s.terminate()
+	s.mu.Unlock()
+
+	if exited != nil {
+		<-exited
+	}
+}
+
+func (s *WhisperServer) stopIdle() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.users > 0 || s.cmd == nil {
+		return
+	}
+	log.Printf("whisper-server: unused for %v, stopping", s.config.IdleTimeout)
+	s.terminate()
+}
+
+// launch starts a server process; s.mu must be held
+func (s *WhisperServer) launch() {
+	args := []string{
+		"--model", s.config.ModelPath,
+		"--host", "127.0.0.1",
+		"--port", strconv.Itoa(s.config.Port),
+	}
+	if s.config.Threads > 0 {
+		args = append(args, "--threads", strconv.Itoa(s.config.Threads))
+	}
+
+	// No Pdeathsig: it fires when the OS thread that forked the server exits,
+	// which the Go runtime may do while the daemon keeps running. The daemon
+	// stops the server on shutdown instead, and a server left over from a
+	// crashed daemon is reused as an external one on the next start.
+	cmd := exec.Command(s.config.Binary, args...)
+	output := &tailBuffer{}
+	cmd.Stdout = output
+	cmd.Stderr = output
+
+	s.state = ServerStarting
+	s.ready = make(chan struct{})
+	if err := cmd.Start(); err != nil {
+		s.state = ServerFailed
+		s.lastErr = fmt.Errorf("start %s: %w", s.config.Binary, err)
+		log.Printf("whisper-server: %v", s.lastErr)
+		close(s.ready)
+		return
+	}
+	log.Printf("whisper-server: started %s on port %d (pid %d)", s.config.ModelPath, s.config.Port, cmd.Process.Pid)
+
+	s.cmd = cmd
+	exited := make(chan struct{})
+	s.exited = exited
+	go func() {
+		err := cmd.Wait()
+		close(exited)
+		s.onExit(cmd, err, output)
+	}()
+	go s.waitReady(cmd, s.ready, exited, output)
+}
+
+// waitReady polls the server until it has loaded the model
+func (s *WhisperServer) waitReady(cmd *exec.Cmd, ready chan struct{}, exited <-chan struct{}, output *tailBuffer) {
+	defer close(ready)
+
+	start := time.Now()
+	ticker := time.NewTicker(whisperServerPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-exited:
+			s.mu.Lock()
+			s.lastErr = fmt.Errorf("whisper-server exited while loading the model: %s", lastLines(output.String(), 3))
+			s.mu.Unlock()
+			return
+		case <-ticker.C:
+		}
+
+		if s.healthy(context.Background()) {
+			s.mu.Lock()
+			if s.cmd == cmd {
+				s.state = ServerReady
+				log.Printf("whisper-server: ready after %v", time.Since(start).Round(time.Millisecond))
+			}
+			s.mu.Unlock()
+			return
+		}
+
+		if time.Since(start) > whisperServerStartTimeout {
+			s.mu.Lock()
+			if s.cmd == cmd {
+				s.lastErr = fmt.Errorf("whisper-server not ready after %v", whisperServerStartTimeout)
+				s.terminate()
+				s.state = ServerFailed
+			}
+			s.mu.Unlock()
+			return
+		}
+	}
+}
+
+// onExit restarts a server that crashed while in use or idle
+func (s *WhisperServer) onExit(cmd *exec.Cmd, err error, output *tailBuffer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cmd != cmd {
+		return // stopped on purpose
+	}
+	s.cmd = nil
+
+	if s.state == ServerStarting {
+		s.state = ServerFailed
+		log.Printf("whisper-server: exited while loading the model (%v): %s", err, lastLines(output.String(), 3))
+		return
+	}
+
+	log.Printf("whisper-server: crashed (%v): %s", err, lastLines(output.String(), 3))
+	if s.restarts >= whisperServerMaxRestarts {
+		s.state = ServerFailed
+		s.lastErr = fmt.Errorf("whisper-server crashed: %v", err)
+		return
+	}
+	s.restarts++
+	log.Printf("whisper-server: restarting (attempt %d/%d)", s.restarts, whisperServerMaxRestarts)
+	s.launch()
+}
+
+// terminate asks the server to exit and kills it if it does not; s.mu must
+// be held. It returns a channel closed once the process is gone.
+func (s *WhisperServer) terminate() <-chan struct{} {
+	cmd, exited := s.cmd, s.exited
+	s.cmd = nil
+	if s.state != ServerFailed {
+		s.state = ServerStopped
+	}
+	if cmd == nil {
+		return nil
+	}
+
+	_ = cmd.Process.Signal(syscall.SIGTERM)
+	go func() {
+		select {
+		case <-exited:
+		case <-time.After(whisperServerStopTimeout):
+			log.Printf("whisper-server: did not exit after %v, killing it", whisperServerStopTimeout)
+			_ = cmd.Process.Kill()
+		}
+	}()
+	return exited
+}
+
+// healthy reports whether the port answers. Servers without a /health
+// endpoint only listen once the model is loaded, so a 404 counts as ready.
+func (s *WhisperServer) healthy(ctx context.Context) bool {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("http://127.0.0.1:%d/health", s.config.Port), nil)
+	if err != nil {
+		return false
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusNotFound
+}
+
+// tailBuffer keeps the last few kilobytes written to it, enough to explain
+// why a process failed without growing over a long lifetime
+type tailBuffer struct {
+	mu  sync.Mutex
+	buf []byte
+}
+
+const tailBufferSize = 4096
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buf = append(b.buf, p...)
+	if len(b.buf) > tailBufferSize {
+		b.buf = append([]byte(nil), b.buf[len(b.buf)-tailBufferSize:]...)
+	}
+	return len(p), nil
+}
+
+func (b *tailBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return string(b.buf)
+}